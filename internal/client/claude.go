@@ -2,38 +2,173 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"clify/internal/envinfo"
 	"clify/internal/models"
+	"clify/internal/prompt"
 	"clify/internal/safety"
+	"clify/internal/validate"
 	"runtime"
-
-	"github.com/aktagon/llmkit/anthropic"
+	"slices"
+	"strings"
+	"time"
 )
 
-//go:embed system_prompt.txt
-var systemPrompt string
-
 //go:embed command_response_schema.json
 var commandResponseSchema string
 
+//go:embed workflow_response_schema.json
+var workflowResponseSchema string
+
 type ClaudeClient struct {
-	apiKey     string
+	provider   Provider
 	classifier *safety.Classifier
+	env        *envinfo.Info
+	templates  *prompt.Templates
+	target     models.Target
+
+	repairAttempts int
 }
 
 func NewClaudeClient(apiKey string) *ClaudeClient {
+	return NewClaudeClientWithProvider(NewAnthropicProvider(apiKey))
+}
+
+// NewClaudeClientWithProvider creates a client that sends requests through provider
+func NewClaudeClientWithProvider(provider Provider) *ClaudeClient {
 	classifier := safety.NewClassifier()
 	
 	return &ClaudeClient{
-		apiKey:     apiKey,
+		provider:   provider,
 		classifier: classifier,
+		templates:  prompt.Builtin(),
+
+		repairAttempts: DefaultRepairAttempts,
+	}
+}
+
+// NewClaudeClientFromConfig creates a client with the configured provider,
+// environment context and prompt template overrides
+func NewClaudeClientFromConfig(cfg *models.Config) (*ClaudeClient, error) {
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := NewClaudeClientWithProvider(provider)
+
+	if cfg.Context.Enabled {
+		c.SetEnvironment(envinfo.Collect(cfg.Context.Allow))
+	}
+	if !cfg.Target.IsLocal() {
+		c.SetTarget(cfg.Target)
+	}
+	c.SetRepairAttempts(cfg.RepairAttempts)
+
+	dirs := prompt.SearchDirs()
+	if cfg.Prompts != "" {
+		// After ~/.clify/prompts, before the project's
+		dirs = slices.Insert(dirs, min(1, len(dirs)), cfg.Prompts)
+	}
+	templates, err := prompt.Load(dirs)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTemplates(templates)
+
+	return c, nil
+}
+
+// Templates returns the prompt templates in use
+func (c *ClaudeClient) Templates() *prompt.Templates {
+	return c.templates
+}
+
+// CommandResponseSchema returns the JSON schema requested for command responses
+func CommandResponseSchema() string {
+	return commandResponseSchema
+}
+
+// WorkflowResponseSchema returns the JSON schema requested for workflow responses
+func WorkflowResponseSchema() string {
+	return workflowResponseSchema
+}
+
+// SetEnvironment attaches local environment details that are included in every prompt
+func (c *ClaudeClient) SetEnvironment(env *envinfo.Info) {
+	c.env = env
+}
+
+// SetTemplates replaces the builtin prompt templates, e.g. with user overrides
+func (c *ClaudeClient) SetTemplates(templates *prompt.Templates) {
+	c.templates = templates
+}
+
+// SetTarget makes prompts and safety rules describe target instead of the
+// local system. The OS name is normalized to its GOOS spelling.
+func (c *ClaudeClient) SetTarget(target models.Target) {
+	target.OS = NormalizeOS(target.OS)
+	c.target = target
+	c.classifier = safety.NewClassifierForOS(c.goos())
+}
+
+// Target returns the target system commands are generated for
+func (c *ClaudeClient) Target() models.Target {
+	return c.target
+}
+
+// Answer kinds cached separately
+const (
+	KindCommands = ""
+	KindWorkflow = "workflow"
+)
+
+// CacheScope describes everything besides the query that shapes an answer
+// of kind: the model, the target system, the prompt templates and the
+// response schema. A change to any of them invalidates cached answers.
+func (c *ClaudeClient) CacheScope(kind string) models.CacheScope {
+	schema := commandResponseSchema
+	if kind == KindWorkflow {
+		schema = workflowResponseSchema
+	}
+	sum := sha256.Sum256([]byte(schema))
+
+	return models.CacheScope{
+		Kind:   kind,
+		Model:  providerModel(c.provider),
+		OS:     c.goos(),
+		Shell:  c.target.Shell,
+		Distro: c.target.Distro,
+		Prompt: c.templates.Hash(),
+		Schema: hex.EncodeToString(sum[:])[:12],
+	}
+}
+
+// NormalizeOS maps common OS spellings such as "macos" to GOOS names
+func NormalizeOS(name string) string {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "macos", "mac", "osx":
+		return "darwin"
+	case "win":
+		return "windows"
+	default:
+		return name
+	}
+}
+
+func (c *ClaudeClient) goos() string {
+	if c.target.OS != "" {
+		return c.target.OS
 	}
+	return runtime.GOOS
 }
 
-func (c *ClaudeClient) getOSInfo() string {
-	osName := runtime.GOOS
+// OSInfo returns the operating system name used in prompts
+func (c *ClaudeClient) OSInfo() string {
+	osName := c.goos()
 	switch osName {
 	case "darwin":
 		return "macOS"
@@ -52,46 +187,79 @@ func (c *ClaudeClient) getOSInfo() string {
 	}
 }
 
-func (c *ClaudeClient) QueryCommands(ctx context.Context, query string) (*models.Response, error) {
-	osInfo := c.getOSInfo()
-	
-	prompt := fmt.Sprintf(systemPrompt, osInfo, runtime.GOARCH, query, osInfo)
+// BuildPrompt renders the prompt that is sent for the given query
+func (c *ClaudeClient) BuildPrompt(query string) (string, error) {
+	return c.buildPrompt(query, nil)
+}
 
-	schema := commandResponseSchema
+func (c *ClaudeClient) buildPrompt(query string, unavailable []string) (string, error) {
+	data := c.promptData(query)
+	data.Unavailable = unavailable
+	return c.templates.Render(prompt.CommandTemplate, data)
+}
 
-	response, err := anthropic.Prompt("You are a helpful command-line assistant.", prompt, schema, c.apiKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query Claude: %w", err)
+// promptData describes the target system. Local details such as the
+// architecture and environment context are only included when the target
+// OS is the local one.
+func (c *ClaudeClient) promptData(query string) prompt.Data {
+	data := prompt.Data{
+		OS:     c.OSInfo(),
+		Query:  query,
+		Shell:  c.target.Shell,
+		Distro: c.target.Distro,
+		Remote: c.target.OS != "" && c.target.OS != runtime.GOOS,
+	}
+	if data.Remote {
+		return data
 	}
 
-	if response == "" {
-		return &models.Response{
-			Explanation: "No commands found for the given query",
-			Commands:    []models.Command{},
-		}, nil
+	data.Arch = runtime.GOARCH
+	for _, line := range c.env.Lines() {
+		// Explicit target values replace the detected ones
+		if (data.Shell != "" && strings.HasPrefix(line, "Shell:")) ||
+			(data.Distro != "" && strings.HasPrefix(line, "Distribution:")) {
+			continue
+		}
+		data.Environment = append(data.Environment, line)
 	}
+	return data
+}
 
-	// Parse the full API response to extract the actual content
-	var apiResponse struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
+func (c *ClaudeClient) QueryCommands(ctx context.Context, query string) (*models.Response, error) {
+	prompt, err := c.BuildPrompt(query)
+	if err != nil {
+		return nil, err
 	}
-	
-	if err := json.Unmarshal([]byte(response), &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	return c.queryWithPrompt(ctx, query, prompt)
+}
+
+// QueryAlternatives re-asks a query while telling the model which tools are not installed
+func (c *ClaudeClient) QueryAlternatives(ctx context.Context, query string, unavailable []string) (*models.Response, error) {
+	prompt, err := c.buildPrompt(query, unavailable)
+	if err != nil {
+		return nil, err
 	}
-	
-	if len(apiResponse.Content) == 0 || apiResponse.Content[0].Text == "" {
+	return c.queryWithPrompt(ctx, query+" (without "+strings.Join(unavailable, ", ")+")", prompt)
+}
+
+func (c *ClaudeClient) queryWithPrompt(ctx context.Context, query, prompt string) (*models.Response, error) {
+	jsonContent, usage, repaired, attempts, err := c.completeWithRepair(ctx, Request{
+		Query:  query,
+		System: "You are a helpful command-line assistant.",
+		Prompt: prompt,
+		Schema: commandResponseSchema,
+	}, c.checkCommands)
+	if err != nil {
+		return nil, err
+	}
+
+	if jsonContent == "" {
 		return &models.Response{
 			Explanation: "No commands found for the given query",
 			Commands:    []models.Command{},
+			Usage:       usage,
 		}, nil
 	}
-	
-	// Extract the actual JSON content from the response
-	jsonContent := apiResponse.Content[0].Text
 
 	var result models.Response
 	if err := json.Unmarshal([]byte(jsonContent), &result); err != nil {
@@ -102,19 +270,128 @@ func (c *ClaudeClient) QueryCommands(ctx context.Context, query string) (*models
 		return &models.Response{
 			Explanation: "No commands found for the given query",
 			Commands:    []models.Command{},
+			Usage:       usage,
 		}, nil
 	}
+	result.Usage = usage
+	result.RepairAttempts = attempts
 
-	// Classify safety level for each command
+	// Record which commands were repaired and which still don't parse
+	for _, p := range repaired {
+		if p.index >= 0 && p.index < len(result.Commands) {
+			result.Commands[p.index].Repaired = true
+		}
+	}
 	for i := range result.Commands {
-		safetyLevel := c.classifier.ClassifyCommand(result.Commands[i].Text)
-		result.Commands[i].SafetyLevel = string(safetyLevel)
+		if err := validate.SyntaxError(result.Commands[i].Text, c.shell()); err != nil {
+			result.Commands[i].SyntaxError = err.Error()
+			result.Commands[i].Repaired = false
+		}
 	}
 
+	c.Classify(&result)
 	return &result, nil
 }
 
+// Classify sets the safety level of each command in response
+func (c *ClaudeClient) Classify(response *models.Response) {
+	for i := range response.Commands {
+		safetyLevel := c.classifier.ClassifyCommand(response.Commands[i].Text)
+		response.Commands[i].SafetyLevel = string(safetyLevel)
+	}
+}
+
+// QueryWorkflow asks for an ordered sequence of steps that accomplish query
+func (c *ClaudeClient) QueryWorkflow(ctx context.Context, query string) (*models.Workflow, error) {
+	prompt, err := c.templates.Render(prompt.WorkflowTemplate, c.promptData(query))
+	if err != nil {
+		return nil, err
+	}
+
+	jsonContent, usage, _, attempts, err := c.completeWithRepair(ctx, Request{
+		Query:  query,
+		System: "You are a helpful command-line assistant.",
+		Prompt: prompt,
+		Schema: workflowResponseSchema,
+	}, c.checkWorkflow)
+	if err != nil {
+		return nil, err
+	}
+
+	if jsonContent == "" {
+		return &models.Workflow{
+			Explanation: "No steps found for the given query",
+			Steps:       []models.WorkflowStep{},
+			Usage:       usage,
+		}, nil
+	}
+
+	var result models.Workflow
+	if err := json.Unmarshal([]byte(jsonContent), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	result.Usage = usage
+	result.RepairAttempts = attempts
+
+	// Classify safety level for each step
+	for i := range result.Steps {
+		safetyLevel := c.classifier.ClassifyCommand(result.Steps[i].Text)
+		result.Steps[i].SafetyLevel = string(safetyLevel)
+	}
+
+	return &result, nil
+}
+
+// complete sends req and returns the text of the first content block along
+// with the token usage reported by the API
+func (c *ClaudeClient) complete(ctx context.Context, req Request) (string, *models.Usage, error) {
+	response, err := c.provider.Complete(ctx, req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to query Claude: %w", err)
+	}
+
+	if response == "" {
+		return "", nil, nil
+	}
+
+	// Parse the full API response to extract the actual content
+	var apiResponse struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.Unmarshal([]byte(response), &apiResponse); err != nil {
+		return "", nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	usage := &models.Usage{
+		Model:        apiResponse.Model,
+		InputTokens:  apiResponse.Usage.InputTokens,
+		OutputTokens: apiResponse.Usage.OutputTokens,
+		CostUSD:      EstimateCost(apiResponse.Model, apiResponse.Usage.InputTokens, apiResponse.Usage.OutputTokens),
+		Unpriced:     !KnownPrice(apiResponse.Model),
+		Timestamp:    time.Now(),
+	}
+
+	if len(apiResponse.Content) == 0 {
+		return "", usage, nil
+	}
+
+	// Extract the actual JSON content from the response
+	return apiResponse.Content[0].Text, usage, nil
+}
+
 func (c *ClaudeClient) TestConnection(ctx context.Context) error {
-	_, err := anthropic.Prompt("You are a helpful assistant.", "Respond with exactly: 'Connection successful'", "", c.apiKey)
+	_, err := c.provider.Complete(ctx, Request{
+		System: "You are a helpful assistant.",
+		Prompt: "Respond with exactly: 'Connection successful'",
+	})
 	return err
 }
\ No newline at end of file
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"clify/internal/client/clienttest"
+	"clify/internal/models"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// command returns a command object with every field the response schema requires
+func command(text, description string) string {
+	return fmt.Sprintf(`{"text": %q, "description": %q, "prerequisites": [], "warnings": [], "confidence": 0.9, "platforms": ["linux"], "docs": []}`, text, description)
+}
+
+func newTestClient(server *clienttest.Server) *ClaudeClient {
+	provider := NewAnthropicProvider("test-key")
+	provider.Endpoint = server.URL
+	return NewClaudeClientWithProvider(provider)
+}
+
+func TestQueryCommands(t *testing.T) {
+	server := clienttest.NewServer(clienttest.Message(`{
+		"explanation": "List files",
+		"commands": [
+			` + command("ls -la", "List all files") + `,
+			` + command("rm -rf /", "Do not run this") + `
+		]
+	}`))
+	defer server.Close()
+
+	response, err := newTestClient(server).QueryCommands(context.Background(), "list files")
+	if err != nil {
+		t.Fatalf("QueryCommands() error = %v", err)
+	}
+
+	if len(response.Commands) != 2 {
+		t.Fatalf("len(Commands) = %d, want 2", len(response.Commands))
+	}
+	if got := response.Commands[0].SafetyLevel; got != string(models.SafetyLevelSafe) {
+		t.Errorf("Commands[0].SafetyLevel = %q, want %q", got, models.SafetyLevelSafe)
+	}
+	if got := response.Commands[1].SafetyLevel; got != string(models.SafetyLevelDangerous) {
+		t.Errorf("Commands[1].SafetyLevel = %q, want %q", got, models.SafetyLevelDangerous)
+	}
+	if response.Usage == nil || response.Usage.InputTokens != 100 || response.Usage.OutputTokens != 50 {
+		t.Errorf("Usage = %+v, want 100 input and 50 output tokens", response.Usage)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].APIKey != "test-key" {
+		t.Fatalf("server received %+v, want one request with the API key", requests)
+	}
+}
+
+func TestQueryCommandsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   clienttest.Reply
+		wantErr string
+	}{
+		{"malformed content", clienttest.Message("not json"), "failed to parse response"},
+		{"malformed envelope", clienttest.Reply{Status: 200, Body: "{"}, "failed to parse API response"},
+		{"api error", clienttest.Error(529, "overloaded"), "status 529"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := clienttest.NewServer(tt.reply)
+			defer server.Close()
+
+			_, err := newTestClient(server).QueryCommands(context.Background(), "list files")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("QueryCommands() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryCommandsEmpty(t *testing.T) {
+	replies := []clienttest.Reply{
+		clienttest.Empty(),
+		clienttest.Message(`{"explanation": "Nothing", "commands": []}`),
+	}
+
+	for _, reply := range replies {
+		server := clienttest.NewServer(reply)
+
+		response, err := newTestClient(server).QueryCommands(context.Background(), "do nothing")
+		server.Close()
+		if err != nil {
+			t.Fatalf("QueryCommands() error = %v", err)
+		}
+		if len(response.Commands) != 0 || response.Explanation != "No commands found for the given query" {
+			t.Errorf("QueryCommands() = %+v, want empty result", response)
+		}
+	}
+}
+
+func TestQueryCommandsRepair(t *testing.T) {
+	server := clienttest.NewServer(
+		clienttest.Message(`{"explanation": "Find logs", "commands": [`+command("find . -name '*.log", "Find log files")+`, `+command("ls", "List files")+`]}`),
+		clienttest.Message(`{"explanation": "Find logs", "commands": [`+command("find . -name '*.log'", "Find log files")+`, `+command("ls", "List files")+`]}`),
+	)
+	defer server.Close()
+
+	response, err := newTestClient(server).QueryCommands(context.Background(), "find logs")
+	if err != nil {
+		t.Fatalf("QueryCommands() error = %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("server received %d requests, want 2", len(requests))
+	}
+	if !strings.Contains(fmt.Sprint(requests[1].Body), "syntax error") {
+		t.Error("repair request does not describe the syntax error")
+	}
+	if response.RepairAttempts != 1 {
+		t.Errorf("RepairAttempts = %d, want 1", response.RepairAttempts)
+	}
+	if !response.Commands[0].Repaired || response.Commands[1].Repaired {
+		t.Errorf("Repaired = %v, %v, want true, false", response.Commands[0].Repaired, response.Commands[1].Repaired)
+	}
+	if response.Usage.InputTokens != 200 {
+		t.Errorf("Usage.InputTokens = %d, want usage of both requests", response.Usage.InputTokens)
+	}
+}
+
+func TestQueryCommandsRepairGivesUp(t *testing.T) {
+	server := clienttest.NewServer(
+		clienttest.Message(`{"explanation": "Quote", "commands": [` + command("echo 'unterminated", "Print") + `]}`),
+	)
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetRepairAttempts(1)
+	response, err := client.QueryCommands(context.Background(), "quote")
+	if err != nil {
+		t.Fatalf("QueryCommands() error = %v", err)
+	}
+
+	if len(server.Requests()) != 2 {
+		t.Errorf("server received %d requests, want 2", len(server.Requests()))
+	}
+	if response.Commands[0].SyntaxError == "" || response.Commands[0].Repaired {
+		t.Errorf("Commands[0] = %+v, want an unrepaired syntax error", response.Commands[0])
+	}
+
+	client.SetRepairAttempts(0)
+	if _, err := client.QueryCommands(context.Background(), "quote"); err != nil {
+		t.Fatalf("QueryCommands() error = %v", err)
+	}
+	if len(server.Requests()) != 3 {
+		t.Errorf("server received %d requests with repair disabled, want 3", len(server.Requests()))
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := clienttest.NewServer(clienttest.Message(`{"explanation": "Show date", "commands": [` + command("date", "Print the date") + `]}`))
+	defer server.Close()
+
+	dir := t.TempDir()
+	live := NewAnthropicProvider("test-key")
+	live.Endpoint = server.URL
+
+	recorder := NewClaudeClientWithProvider(NewRecordingProvider(live, dir))
+	if _, err := recorder.QueryCommands(context.Background(), "show date"); err != nil {
+		t.Fatalf("recording QueryCommands() error = %v", err)
+	}
+
+	replayer := NewClaudeClientWithProvider(NewReplayProvider(dir))
+	response, err := replayer.QueryCommands(context.Background(), "show date")
+	if err != nil {
+		t.Fatalf("replaying QueryCommands() error = %v", err)
+	}
+	if len(response.Commands) != 1 || response.Commands[0].Text != "date" {
+		t.Errorf("replayed Commands = %+v, want [date]", response.Commands)
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("server received %d requests, want 1", len(server.Requests()))
+	}
+
+	if _, err := replayer.QueryCommands(context.Background(), "unrecorded query"); err == nil {
+		t.Error("replaying an unrecorded query should fail")
+	}
+}
+
+func TestNewProviderUsesConfiguredModel(t *testing.T) {
+	server := clienttest.NewServer(clienttest.Message(`{"explanation": "Show date", "commands": [` + command("date", "Print the date") + `]}`))
+	defer server.Close()
+
+	provider, err := NewProvider(&models.Config{APIKey: "test-key", Endpoint: server.URL, Model: "claude-profile-model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClaudeClientWithProvider(provider).QueryCommands(context.Background(), "show date"); err != nil {
+		t.Fatalf("QueryCommands() error = %v", err)
+	}
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Body["model"] != "claude-profile-model" {
+		t.Errorf("requests = %+v, want one for the configured model", requests)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected float64
+	}{
+		{"claude-sonnet-4-20250514", 0.0105},
+		{"claude-3-5-haiku-20241022", 0.0028},
+		{"claude-3-haiku-20240307", 0.000875},
+		{"claude-haiku-4-5", 0.0035},
+		{"unknown-model", 0},
+	}
+
+	for _, tt := range tests {
+		result := EstimateCost(tt.model, 1000, 500)
+		if diff := result - tt.expected; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("EstimateCost(%q) = %v, want %v", tt.model, result, tt.expected)
+		}
+		if known := KnownPrice(tt.model); known != (tt.expected != 0) {
+			t.Errorf("KnownPrice(%q) = %v", tt.model, known)
+		}
+	}
+}
+
+func TestBuildPromptForTarget(t *testing.T) {
+	c := NewClaudeClientWithProvider(NewReplayProvider(t.TempDir()))
+	c.SetTarget(models.Target{OS: "Windows", Shell: "powershell"})
+
+	prompt, err := c.BuildPrompt("delete temp files")
+	if err != nil {
+		t.Fatalf("BuildPrompt() error = %v", err)
+	}
+	for _, want := range []string{"- Operating System: Windows", "- Shell: powershell", "different Windows system"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("BuildPrompt() missing %q in:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "Architecture:") {
+		t.Errorf("BuildPrompt() includes the local architecture for a remote target:\n%s", prompt)
+	}
+	if got := c.Target().OS; got != "windows" {
+		t.Errorf("Target().OS = %q, want windows", got)
+	}
+}
@@ -0,0 +1,117 @@
+// Package clienttest provides a local stand-in for the Anthropic messages
+// endpoint so clients can be tested offline.
+package clienttest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Reply is a canned HTTP response returned by the server
+type Reply struct {
+	Status int
+	Body   string
+}
+
+// Message returns a successful reply whose first content block contains text
+func Message(text string) Reply {
+	return Reply{Status: http.StatusOK, Body: MessageBody(text, 100, 50)}
+}
+
+// Empty returns a successful reply without any content blocks
+func Empty() Reply {
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":      "msg_test",
+		"type":    "message",
+		"role":    "assistant",
+		"model":   "claude-sonnet-4-20250514",
+		"content": []interface{}{},
+		"usage":   map[string]int{"input_tokens": 10, "output_tokens": 0},
+	})
+	return Reply{Status: http.StatusOK, Body: string(body)}
+}
+
+// Error returns an API error reply with the given status
+func Error(status int, message string) Reply {
+	body, _ := json.Marshal(map[string]interface{}{
+		"type":  "error",
+		"error": map[string]string{"type": "api_error", "message": message},
+	})
+	return Reply{Status: status, Body: string(body)}
+}
+
+// MessageBody builds a messages API response body containing text
+func MessageBody(text string, inputTokens, outputTokens int) string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":          "msg_test",
+		"type":        "message",
+		"role":        "assistant",
+		"model":       "claude-sonnet-4-20250514",
+		"stop_reason": "end_turn",
+		"content": []map[string]string{
+			{"type": "text", "text": text},
+		},
+		"usage": map[string]int{
+			"input_tokens":  inputTokens,
+			"output_tokens": outputTokens,
+		},
+	})
+	return string(body)
+}
+
+// ReceivedRequest is a request captured by the server
+type ReceivedRequest struct {
+	APIKey string
+	Body   map[string]interface{}
+}
+
+// Server replays queued replies in order and records incoming requests.
+// Once the queue is exhausted the last reply is repeated.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	replies  []Reply
+	requests []ReceivedRequest
+}
+
+func NewServer(replies ...Reply) *Server {
+	s := &Server{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []ReceivedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReceivedRequest(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	var body map[string]interface{}
+	json.Unmarshal(data, &body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, ReceivedRequest{APIKey: r.Header.Get("x-api-key"), Body: body})
+	reply := Error(http.StatusInternalServerError, "no reply queued")
+	if len(s.replies) > 0 {
+		reply = s.replies[0]
+		if len(s.replies) > 1 {
+			s.replies = s.replies[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.Header.Get("x-api-key") == "" {
+		reply = Error(http.StatusUnauthorized, "missing API key")
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(reply.Status)
+	io.WriteString(w, reply.Body)
+}
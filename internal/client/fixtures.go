@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Fixture is a recorded request/response pair stored as JSON
+type Fixture struct {
+	Request  Request `json:"request"`
+	Response string  `json:"response"`
+}
+
+// FixtureKey identifies a request in a fixtures directory. Fixtures are keyed
+// by the user query and schema rather than the full prompt so that recordings
+// replay on any operating system.
+func FixtureKey(req Request) string {
+	key := req.Query
+	if key == "" {
+		key = req.System + "\x00" + req.Prompt
+	}
+	sum := sha256.Sum256([]byte(key + "\x00" + req.Schema))
+	return hex.EncodeToString(sum[:8])
+}
+
+func fixturePath(dir string, req Request) string {
+	return filepath.Join(dir, FixtureKey(req)+".json")
+}
+
+// ReplayProvider answers requests from recorded fixtures without network access
+type ReplayProvider struct {
+	dir string
+}
+
+func NewReplayProvider(dir string) *ReplayProvider {
+	return &ReplayProvider{dir: dir}
+}
+
+func (p *ReplayProvider) Complete(ctx context.Context, req Request) (string, error) {
+	path := fixturePath(p.dir, req)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no fixture recorded for query %q (expected %s)", req.Query, path)
+		}
+		return "", fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return "", fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	return fixture.Response, nil
+}
+
+// RecordingProvider forwards requests to another provider and stores every
+// successful interaction as a fixture
+type RecordingProvider struct {
+	next Provider
+	dir  string
+}
+
+func NewRecordingProvider(next Provider, dir string) *RecordingProvider {
+	return &RecordingProvider{next: next, dir: dir}
+}
+
+func (p *RecordingProvider) Complete(ctx context.Context, req Request) (string, error) {
+	response, err := p.next.Complete(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if err := SaveFixture(p.dir, Fixture{Request: req, Response: response}); err != nil {
+		return "", err
+	}
+	return response, nil
+}
+
+// SaveFixture writes fixture into dir under its request key
+func SaveFixture(dir string, fixture Fixture) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %w", err)
+	}
+
+	if err := os.WriteFile(fixturePath(dir, fixture.Request), data, 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
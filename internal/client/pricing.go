@@ -0,0 +1,49 @@
+package client
+
+import "strings"
+
+// modelPrice is the USD price per million input and output tokens
+type modelPrice struct {
+	prefix string
+	input  float64
+	output float64
+}
+
+// modelPrices is matched by longest prefix against the model reported by the API
+var modelPrices = []modelPrice{
+	{"claude-opus-4", 15.00, 75.00},
+	{"claude-sonnet-4", 3.00, 15.00},
+	{"claude-haiku-4", 1.00, 5.00},
+	{"claude-3-7-sonnet", 3.00, 15.00},
+	{"claude-3-5-sonnet", 3.00, 15.00},
+	{"claude-3-5-haiku", 0.80, 4.00},
+	{"claude-3-opus", 15.00, 75.00},
+	{"claude-3-sonnet", 3.00, 15.00},
+	{"claude-3-haiku", 0.25, 1.25},
+}
+
+// EstimateCost returns the USD cost of a call, or 0 for unknown models
+func EstimateCost(model string, inputTokens, outputTokens int) float64 {
+	price := priceOf(model)
+	if price == nil {
+		return 0
+	}
+	return (float64(inputTokens)*price.input + float64(outputTokens)*price.output) / 1_000_000
+}
+
+// KnownPrice reports whether EstimateCost knows the price of model
+func KnownPrice(model string) bool {
+	return priceOf(model) != nil
+}
+
+// priceOf returns the price with the longest prefix of model, or nil
+func priceOf(model string) *modelPrice {
+	var best *modelPrice
+	for i := range modelPrices {
+		price := &modelPrices[i]
+		if strings.HasPrefix(model, price.prefix) && (best == nil || len(price.prefix) > len(best.prefix)) {
+			best = price
+		}
+	}
+	return best
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"clify/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aktagon/llmkit"
+	"github.com/aktagon/llmkit/anthropic"
+)
+
+// Provider names accepted in config
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderReplay    = "replay"
+	ProviderRecord    = "record"
+)
+
+// Request is a single prompt sent to a provider
+type Request struct {
+	Query  string `json:"query"` // the user's query, used to key fixtures
+	System string `json:"system"`
+	Prompt string `json:"prompt"`
+	Schema string `json:"schema,omitempty"`
+}
+
+// Provider sends a request and returns the raw Anthropic messages API response body
+type Provider interface {
+	Complete(ctx context.Context, req Request) (string, error)
+}
+
+// NewProvider builds the provider selected in config
+func NewProvider(cfg *models.Config) (Provider, error) {
+	anthropicProvider := NewAnthropicProvider(cfg.APIKey)
+	if cfg.Endpoint != "" {
+		anthropicProvider.Endpoint = cfg.Endpoint
+	}
+	if cfg.Model != "" {
+		anthropicProvider.Model = cfg.Model
+	}
+
+	switch cfg.Provider {
+	case "", ProviderAnthropic:
+		return anthropicProvider, nil
+	case ProviderReplay:
+		if cfg.Fixtures == "" {
+			return nil, fmt.Errorf("provider %q requires a fixtures directory", cfg.Provider)
+		}
+		return NewReplayProvider(cfg.Fixtures), nil
+	case ProviderRecord:
+		if cfg.Fixtures == "" {
+			return nil, fmt.Errorf("provider %q requires a fixtures directory", cfg.Provider)
+		}
+		return NewRecordingProvider(anthropicProvider, cfg.Fixtures), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+	}
+}
+
+// providerModel returns the model p sends requests to, or "" for providers
+// that answer without one
+func providerModel(p Provider) string {
+	switch p := p.(type) {
+	case *AnthropicProvider:
+		return p.Model
+	case *RecordingProvider:
+		return providerModel(p.next)
+	default:
+		return ""
+	}
+}
+
+// RequestTimeout bounds a single API call, so that a stalled connection
+// does not hang the TUI
+const RequestTimeout = 2 * time.Minute
+
+// AnthropicProvider calls the Anthropic messages API over HTTP
+type AnthropicProvider struct {
+	APIKey     string
+	Endpoint   string
+	Model      string
+	MaxTokens  int
+	HTTPClient *http.Client
+}
+
+func NewAnthropicProvider(apiKey string) *AnthropicProvider {
+	return &AnthropicProvider{
+		APIKey:     apiKey,
+		Endpoint:   anthropic.Endpoint,
+		Model:      anthropic.Model,
+		MaxTokens:  anthropic.MaxTokens,
+		HTTPClient: &http.Client{Timeout: RequestTimeout},
+	}
+}
+
+func (p *AnthropicProvider) Complete(ctx context.Context, req Request) (string, error) {
+	if p.APIKey == "" {
+		return "", &llmkit.ValidationError{
+			Field:   "apiKey",
+			Message: "API key is required",
+		}
+	}
+
+	userPrompt := req.Prompt
+	if req.Schema != "" {
+		userPrompt = fmt.Sprintf("You must output only the raw JSON without further explanation or formatting. %s\n\nUse the following JSON schema for the output format:\n\n%s", req.Prompt, req.Schema)
+	}
+
+	requestBody := map[string]interface{}{
+		"model":      p.Model,
+		"max_tokens": p.MaxTokens,
+		"messages": []map[string]string{
+			{"role": "user", "content": userPrompt},
+		},
+	}
+	if req.System != "" {
+		requestBody["system"] = req.System
+	}
+
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", &llmkit.RequestError{Operation: "marshaling request body", Err: err}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.Endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return "", &llmkit.RequestError{Operation: "creating request", Err: err}
+	}
+	httpReq.Header.Set("x-api-key", p.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropic.AnthropicVersion)
+	httpReq.Header.Set("content-type", "application/json")
+
+	resp, err := p.HTTPClient.Do(httpReq)
+	if err != nil {
+		return "", &llmkit.RequestError{Operation: "sending request", Err: err}
+	}
+	defer resp.Body.Close()
+
+	bodyText, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", &llmkit.RequestError{Operation: "reading response", Err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &llmkit.APIError{
+			Provider:   "Anthropic",
+			StatusCode: resp.StatusCode,
+			Message:    string(bodyText),
+			Endpoint:   p.Endpoint,
+		}
+	}
+
+	return string(bodyText), nil
+}
+
+// IsUnreachable reports whether err means the provider could not be reached,
+// as opposed to the provider rejecting or failing the request
+func IsUnreachable(err error) bool {
+	var requestErr *llmkit.RequestError
+	if errors.As(err, &requestErr) && requestErr.Operation == "sending request" {
+		return !errors.Is(err, context.Canceled)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"clify/internal/models"
+	"clify/internal/validate"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultRepairAttempts bounds how often an invalid response is sent back
+// to the model for repair
+const DefaultRepairAttempts = 2
+
+// problem is a validation error in a response. Index is the command or step
+// it concerns, or -1 when it concerns the whole response.
+type problem struct {
+	index   int
+	message string
+}
+
+// SetRepairAttempts sets how many repair requests may follow an invalid response
+func (c *ClaudeClient) SetRepairAttempts(attempts int) {
+	c.repairAttempts = attempts
+}
+
+// completeWithRepair sends req and, while check reports problems, asks the
+// model to correct its previous answer. It returns the last answer, the
+// combined usage of all calls, the problems that were sent for repair and the
+// number of repair requests made. A failing repair request keeps the last answer.
+func (c *ClaudeClient) completeWithRepair(ctx context.Context, req Request, check func(string) []problem) (string, *models.Usage, []problem, int, error) {
+	text, usage, err := c.complete(ctx, req)
+	if err != nil {
+		return "", nil, nil, 0, err
+	}
+
+	var repaired []problem
+	attempts := 0
+	for attempts < c.repairAttempts {
+		problems := check(text)
+		if len(problems) == 0 {
+			break
+		}
+		attempts++
+
+		repairReq := req
+		repairReq.Query = fmt.Sprintf("%s (repair %d)", req.Query, attempts)
+		repairReq.Prompt = repairPrompt(req.Prompt, text, problems)
+
+		repairedText, repairUsage, err := c.complete(ctx, repairReq)
+		if err != nil {
+			break
+		}
+		repaired = append(repaired, problems...)
+		text = repairedText
+		usage = addUsage(usage, repairUsage)
+	}
+
+	return text, usage, repaired, attempts, nil
+}
+
+func repairPrompt(original, previous string, problems []problem) string {
+	var b strings.Builder
+	b.WriteString(original)
+	b.WriteString("\n\nYour previous response was:\n\n")
+	b.WriteString(previous)
+	b.WriteString("\n\nIt has the following problems:\n")
+	for _, p := range problems {
+		b.WriteString("- ")
+		b.WriteString(p.message)
+		b.WriteString("\n")
+	}
+	b.WriteString("\nReturn a corrected JSON object that fixes every problem. Keep commands that had no problems unchanged.")
+	return b.String()
+}
+
+func addUsage(a, b *models.Usage) *models.Usage {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	sum := *a
+	sum.InputTokens += b.InputTokens
+	sum.OutputTokens += b.OutputTokens
+	sum.CostUSD += b.CostUSD
+	return &sum
+}
+
+// shell is the shell dialect commands are syntax-checked against
+func (c *ClaudeClient) shell() string {
+	if c.target.Shell != "" {
+		return c.target.Shell
+	}
+	if c.goos() == "windows" {
+		return "powershell"
+	}
+	return ""
+}
+
+// checkCommands validates a command response against its schema and checks
+// every command for shell syntax errors
+func (c *ClaudeClient) checkCommands(text string) []problem {
+	if text == "" {
+		return nil
+	}
+	if problems := c.checkSchema(commandResponseSchema, text); len(problems) > 0 {
+		return problems
+	}
+
+	var response models.Response
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		return []problem{{index: -1, message: fmt.Sprintf("response is not valid JSON: %v", err)}}
+	}
+
+	var problems []problem
+	for i, cmd := range response.Commands {
+		if err := validate.SyntaxError(cmd.Text, c.shell()); err != nil {
+			problems = append(problems, problem{index: i, message: fmt.Sprintf("command %d (%s) has a syntax error: %v", i+1, cmd.Text, err)})
+		}
+	}
+	return problems
+}
+
+// checkWorkflow validates a workflow response and the syntax of every step
+func (c *ClaudeClient) checkWorkflow(text string) []problem {
+	if text == "" {
+		return nil
+	}
+	if problems := c.checkSchema(workflowResponseSchema, text); len(problems) > 0 {
+		return problems
+	}
+
+	var workflow models.Workflow
+	if err := json.Unmarshal([]byte(text), &workflow); err != nil {
+		return []problem{{index: -1, message: fmt.Sprintf("response is not valid JSON: %v", err)}}
+	}
+
+	var problems []problem
+	for i, step := range workflow.Steps {
+		if err := validate.SyntaxError(step.Text, c.shell()); err != nil {
+			problems = append(problems, problem{index: i, message: fmt.Sprintf("step %d (%s) has a syntax error: %v", step.ID, step.Text, err)})
+		}
+	}
+	return problems
+}
+
+func (c *ClaudeClient) checkSchema(schema, text string) []problem {
+	messages, err := validate.ValidateSchema(schema, text)
+	if err != nil {
+		return nil
+	}
+
+	problems := make([]problem, len(messages))
+	for i, message := range messages {
+		problems[i] = problem{index: -1, message: message}
+	}
+	return problems
+}
@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"clify/internal/client"
+	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/transfer"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type CacheCommand struct{}
+
+func NewCacheCommand() *CacheCommand {
+	return &CacheCommand{}
+}
+
+// Run handles "cache stats", "cache clear", "cache compact",
+// "cache pin|unpin <query>" and "cache export|import"
+func (c *CacheCommand) Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: clify cache stats|clear|compact|pin <query>|unpin <query>|export|import FILE")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cache, err := config.NewCacheManagerFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "stats":
+		return c.stats(cfg, cache)
+	case "clear":
+		if err := cache.Clear(); err != nil {
+			return err
+		}
+		fmt.Println("Cache cleared.")
+		return nil
+	case "compact":
+		evicted, err := cache.Compact()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d entries; %d remain.\n", evicted, cache.Size())
+		return nil
+	case "pin", "unpin":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: clify cache %s <query>", args[0])
+		}
+		query := strings.Join(args[1:], " ")
+		changed, err := cache.PinQuery(query, args[0] == "pin")
+		if err != nil {
+			return err
+		}
+		if changed == 0 {
+			return fmt.Errorf("nothing to %s for %q", args[0], query)
+		}
+		verb := "Pinned"
+		if args[0] == "unpin" {
+			verb = "Unpinned"
+		}
+		fmt.Printf("%s %d entries for %q.\n", verb, changed, query)
+		return nil
+	case "export":
+		e, err := parseExportFlags("cache", args[1:])
+		if err != nil {
+			return err
+		}
+		entries := e.options.Cache(cache.Entries())
+		return e.write(len(entries), func(w io.Writer) error {
+			return transfer.WriteCache(w, entries, e.format)
+		})
+	case "import":
+		i, err := parseImportFlags("cache", args[1:])
+		if err != nil {
+			return err
+		}
+		var entries []models.CacheEntry
+		if err := i.read(func(r io.Reader) (err error) {
+			entries, err = transfer.ReadCache(r, i.format)
+			return err
+		}); err != nil {
+			return err
+		}
+		result, err := cache.Import(i.options.Cache(entries), i.strategy)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported cache: %s.\n", result)
+		return nil
+	default:
+		return fmt.Errorf("unknown cache command %q; use stats, clear, compact, pin, unpin, export or import", args[0])
+	}
+}
+
+// dimension is one part of the cache key shown in stats
+type dimension struct {
+	name  string
+	value func(models.CacheScope) string
+}
+
+var dimensions = []dimension{
+	{"Kind", func(s models.CacheScope) string {
+		if s.Kind == client.KindCommands {
+			return "commands"
+		}
+		return s.Kind
+	}},
+	{"Model", func(s models.CacheScope) string { return s.Model }},
+	{"OS", func(s models.CacheScope) string { return s.OS }},
+	{"Shell", func(s models.CacheScope) string { return s.Shell }},
+	{"Distro", func(s models.CacheScope) string { return s.Distro }},
+	{"Prompt", func(s models.CacheScope) string { return s.Prompt }},
+	{"Schema", func(s models.CacheScope) string { return s.Schema }},
+}
+
+// stats prints entry counts per key dimension, marking the values the
+// current configuration would look up
+func (c *CacheCommand) stats(cfg *models.Config, cache *config.CacheManager) error {
+	entries := cache.Entries()
+
+	fmt.Println("clify Cache")
+	fmt.Println("===========")
+	fmt.Println()
+
+	if len(entries) == 0 {
+		fmt.Println("The cache is empty.")
+		return nil
+	}
+
+	current := currentScopes(cfg)
+	live, expired, pinned := 0, 0, 0
+	for _, entry := range entries {
+		if entry.Pinned {
+			pinned++
+		}
+		switch {
+		case cache.Expired(entry):
+			expired++
+		case entry.CacheScope == current[0] || entry.CacheScope == current[1]:
+			live++
+		}
+	}
+	fmt.Printf("Entries: %d (%d served with the current configuration, %d expired, %d pinned)\n", len(entries), live, expired, pinned)
+	fmt.Printf("Limits:  ttl %s, %s entries, %s\n\n", valueOr(cfg.Cache.TTL, "never"), limitOr(cfg.Cache.MaxEntries), valueOr(cfg.Cache.MaxSize, "no size limit"))
+
+	for _, dim := range dimensions {
+		counts := make(map[string]int)
+		for _, entry := range entries {
+			counts[dim.value(entry.CacheScope)]++
+		}
+
+		values := make([]string, 0, len(counts))
+		for value := range counts {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+
+		fmt.Printf("%s:\n", dim.name)
+		for _, value := range values {
+			label := value
+			if label == "" {
+				label = "(none)"
+			}
+			marker := ""
+			if value == dim.value(current[0]) || value == dim.value(current[1]) {
+				marker = " *"
+			}
+			fmt.Printf("  %-24s %6d%s\n", label, counts[value], marker)
+		}
+		fmt.Println()
+	}
+	fmt.Println("* matches the current configuration")
+	return nil
+}
+
+// currentScopes returns the command and workflow scopes the current
+// configuration looks up, or empty scopes when no client can be built
+func currentScopes(cfg *models.Config) [2]models.CacheScope {
+	var scopes [2]models.CacheScope
+	claudeClient, err := client.NewClaudeClientFromConfig(cfg)
+	if err != nil {
+		return scopes
+	}
+	scopes[0] = claudeClient.CacheScope(client.KindCommands)
+	scopes[1] = claudeClient.CacheScope(client.KindWorkflow)
+	return scopes
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" || value == "0" {
+		return fallback
+	}
+	return value
+}
+
+func limitOr(limit int) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprint(limit)
+}
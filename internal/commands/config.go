@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"clify/internal/config"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type ConfigCommand struct{}
+
+func NewConfigCommand() *ConfigCommand {
+	return &ConfigCommand{}
+}
+
+// Run handles "config show [--origin]"
+func (c *ConfigCommand) Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: clify config show [--origin]")
+	}
+
+	switch args[0] {
+	case "show":
+		return c.show(args[1:])
+	default:
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
+
+// show prints every effective setting and, with --origin, the layer it
+// came from
+func (c *ConfigCommand) show(args []string) error {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	origin := fs.Bool("origin", false, "show where each value comes from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := config.Resolve()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	keyWidth, valueWidth := 0, 0
+	values := make([]string, len(r.Settings))
+	for i, setting := range r.Settings {
+		values[i] = formatSetting(setting)
+		keyWidth = max(keyWidth, len(setting.Key))
+		valueWidth = max(valueWidth, len(values[i]))
+	}
+	for i, setting := range r.Settings {
+		if *origin {
+			fmt.Printf("%-*s  %-*s  %s\n", keyWidth, setting.Key, valueWidth, values[i], setting.Origin)
+		} else {
+			fmt.Printf("%-*s  %s\n", keyWidth, setting.Key, values[i])
+		}
+	}
+	if r.Config.Profile != "" {
+		fmt.Printf("\nProfile: %s\n", r.Config.Profile)
+	}
+	return nil
+}
+
+// formatSetting prints a value, hiding all but the end of the API key
+func formatSetting(setting config.Setting) string {
+	switch value := setting.Value.(type) {
+	case nil:
+		return ""
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = fmt.Sprint(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case string:
+		if setting.Key == "api_key" && len(value) > 8 {
+			return "****" + value[len(value)-4:]
+		} else if setting.Key == "api_key" && value != "" {
+			return "****"
+		}
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"clify/internal/client"
+	"clify/internal/config"
+	"clify/internal/envinfo"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+type ContextCommand struct{}
+
+func NewContextCommand() *ContextCommand {
+	return &ContextCommand{}
+}
+
+// Run prints exactly which environment details would be sent with a query
+func (c *ContextCommand) Run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Println("clify Environment Context")
+	fmt.Println("=========================")
+	fmt.Println()
+
+	fmt.Println("Always sent:")
+	fmt.Printf("  Operating System: %s\n", client.NewClaudeClient(cfg.APIKey).OSInfo())
+	fmt.Printf("  Architecture: %s\n", runtime.GOARCH)
+	fmt.Println()
+
+	if !cfg.Context.Enabled {
+		fmt.Println("Local context collection is disabled. Nothing else is sent.")
+		fmt.Println()
+		fmt.Println("To enable it, add to ~/.clify/config.yaml:")
+		fmt.Println("  context:")
+		fmt.Println("    enabled: true")
+		fmt.Printf("    allow: [%s]\n", strings.Join(envinfo.DefaultFields, ", "))
+		fmt.Println()
+		fmt.Printf("Available fields: %s\n", strings.Join(envinfo.AllFields, ", "))
+		return nil
+	}
+
+	allow := cfg.Context.Allow
+	if len(allow) == 0 {
+		allow = envinfo.DefaultFields
+	}
+	fmt.Printf("Allowed fields: %s\n", strings.Join(allow, ", "))
+	fmt.Println()
+
+	lines := envinfo.Collect(allow).Lines()
+	if len(lines) == 0 {
+		fmt.Println("No additional details were found.")
+		return nil
+	}
+
+	fmt.Println("Also sent:")
+	for _, line := range lines {
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Println()
+
+	return nil
+}
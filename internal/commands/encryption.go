@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"clify/internal/config"
+	"clify/internal/crypt"
+	"clify/internal/models"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type EncryptionCommand struct{}
+
+func NewEncryptionCommand() *EncryptionCommand {
+	return &EncryptionCommand{}
+}
+
+// Run handles "encryption status", "encryption enable [--key SOURCE]",
+// "encryption migrate" and "encryption disable"
+func (e *EncryptionCommand) Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: clify encryption status|enable [--key passphrase|keyring|file]|migrate|disable")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	switch args[0] {
+	case "status":
+		return e.status(cfg)
+	case "enable":
+		return e.enable(cfg, args[1:])
+	case "migrate":
+		if !cfg.Encryption.Enabled {
+			return fmt.Errorf("encryption is not enabled; run clify encryption enable")
+		}
+		key, err := config.EncryptionKey()
+		if err != nil {
+			return err
+		}
+		return reseal(key, "Encrypted")
+	case "disable":
+		if !cfg.Encryption.Enabled {
+			return fmt.Errorf("encryption is not enabled")
+		}
+		// Unlock the key before forgetting about it
+		if _, err := config.EncryptionKey(); err != nil {
+			return err
+		}
+		if err := reseal(nil, "Decrypted"); err != nil {
+			return err
+		}
+		if err := config.UpdateConfig(map[string]any{"encryption.enabled": false}); err != nil {
+			return err
+		}
+		fmt.Println("Encryption disabled. The key was left in place; delete it yourself if it is no longer needed.")
+		return nil
+	default:
+		return fmt.Errorf("unknown encryption command %q", args[0])
+	}
+}
+
+func (e *EncryptionCommand) status(cfg *models.Config) error {
+	if !cfg.Encryption.Enabled {
+		fmt.Println("Encryption: off")
+		return nil
+	}
+	source, err := crypt.ParseSource(cfg.Encryption.KeySource)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Encryption: on; %s\n", keyLocation(source))
+	if _, err := config.EncryptionKey(); err != nil {
+		fmt.Printf("Key: %v\n", err)
+		return nil
+	}
+	fmt.Println("Key: unlocked")
+	return nil
+}
+
+// enable creates or unlocks the key, encrypts the existing files and turns
+// encryption on in the config
+func (e *EncryptionCommand) enable(cfg *models.Config, args []string) error {
+	fs := flag.NewFlagSet("encryption enable", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	keySource := fs.String("key", crypt.SourceKeyring, "where to keep the key: passphrase, keyring or file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.Encryption.Enabled {
+		return fmt.Errorf("encryption is already enabled; run clify encryption migrate to encrypt files restored in plaintext")
+	}
+
+	source, err := crypt.ParseSource(*keySource)
+	if err != nil {
+		return err
+	}
+	key, source, err := crypt.CreateKey(config.StateDir(), source)
+	if err != nil {
+		return err
+	}
+
+	// Turn encryption on first: plaintext files stay readable with it on,
+	// but encrypted files are unreadable with it off, so a failure in
+	// between must not leave encrypted files behind with encryption off
+	if err := config.UpdateConfig(map[string]any{"encryption.enabled": true, "encryption.key_source": source}); err != nil {
+		return err
+	}
+	config.SetEncryptionKey(key)
+	if err := reseal(key, "Encrypted"); err != nil {
+		return fmt.Errorf("%w; encryption is on, run clify encryption migrate to encrypt the remaining files", err)
+	}
+	fmt.Printf("Encryption enabled; %s.\n", keyLocation(source))
+	return nil
+}
+
+// keyLocation describes where the key from source is kept
+func keyLocation(source string) string {
+	switch source {
+	case crypt.SourcePassphrase:
+		return "the key is derived from your passphrase (set " + crypt.PassphraseEnv + " to skip the prompt)"
+	case crypt.SourceFile:
+		return "the key is kept in " + filepath.Join(config.StateDir(), crypt.KeyFile)
+	default:
+		return "the key is kept in the OS keyring"
+	}
+}
+
+func reseal(key crypt.Key, verb string) error {
+	files, err := config.Reseal(key)
+	for _, file := range files {
+		fmt.Printf("%s %s\n", verb, file)
+	}
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("No cache, history or usage files yet.")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"clify/internal/client"
+	"clify/internal/config"
+	"clify/internal/eval"
+	"clify/internal/models"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+type EvalCommand struct{}
+
+func NewEvalCommand() *EvalCommand {
+	return &EvalCommand{}
+}
+
+// Run evaluates a query suite and compares the result with the previous run
+func (e *EvalCommand) Run(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	suitePath := fs.String("suite", "", "YAML suite to run (default: bundled suite)")
+	fixtures := fs.String("fixtures", "", "replay recorded fixtures from this directory instead of calling the API")
+	baseline := fs.String("baseline", "", "compare against this stored run (default: previous run of the suite)")
+	save := fs.Bool("save", true, "store this run as the suite's previous run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	suite, err := e.loadSuite(*suitePath)
+	if err != nil {
+		return err
+	}
+
+	claudeClient, cfg, err := e.newClient(*fixtures)
+	if err != nil {
+		return err
+	}
+
+	lastRunPath, err := e.lastRunPath(suite.Name)
+	if err != nil {
+		return err
+	}
+	comparePath := *baseline
+	if comparePath == "" {
+		comparePath = lastRunPath
+	}
+	previous, err := eval.LoadRun(comparePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Running suite %q (%d cases)...\n\n", suite.Name, len(suite.Cases))
+	runner := eval.NewRunner(claudeClient, runtime.GOOS)
+	if cfg != nil {
+		// Live runs count against the budget like queries
+		usage := config.NewUsageTracker()
+		usage.SetProfile(cfg.Profile)
+		runner.TrackUsage(usage, cfg.Budget)
+	}
+	run := runner.Run(context.Background(), suite)
+	eval.WriteReport(os.Stdout, run, previous)
+
+	if *save {
+		if err := eval.SaveRun(lastRunPath, run); err != nil {
+			return err
+		}
+		fmt.Printf("Run saved to %s\n", lastRunPath)
+	}
+
+	if run.Passed() < len(run.Results) {
+		return fmt.Errorf("%d of %d cases failed", len(run.Results)-run.Passed(), len(run.Results))
+	}
+	return nil
+}
+
+func (e *EvalCommand) loadSuite(path string) (*eval.Suite, error) {
+	if path == "" {
+		return eval.DefaultSuite()
+	}
+	return eval.LoadSuite(path)
+}
+
+// newClient returns a client replaying fixtures, or one calling the API
+// along with the config it was built from
+func (e *EvalCommand) newClient(fixtures string) (*client.ClaudeClient, *models.Config, error) {
+	if fixtures != "" {
+		return client.NewClaudeClientWithProvider(client.NewReplayProvider(fixtures)), nil, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := config.ResolveAPIKey(cfg); err != nil {
+		return nil, nil, err
+	}
+	claudeClient, err := client.NewClaudeClientFromConfig(cfg)
+	return claudeClient, cfg, err
+}
+
+func (e *EvalCommand) lastRunPath(suite string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, config.DefaultCacheDir, "eval", suite+".json"), nil
+}
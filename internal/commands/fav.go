@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"bufio"
+	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/safety"
+	"clify/internal/transfer"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FavCommand struct {
+	favorites *config.Favorites
+}
+
+func NewFavCommand() *FavCommand {
+	return &FavCommand{
+		favorites: config.NewFavorites(),
+	}
+}
+
+// Run handles "fav list [--tag TAG] [text]", "fav add [flags] <command>",
+// "fav rm <number|command>", "fav run <number|command>" and
+// "fav export|import". Favorites are numbered as list prints them, oldest
+// first.
+func (c *FavCommand) Run(args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return c.list(args[1:])
+	case "add":
+		return c.add(args[1:])
+	case "rm", "remove":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: clify fav rm <number|command>")
+		}
+		favorite, err := c.find(strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		if _, err := c.favorites.Remove(favorite.Command); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", favorite.Command)
+		return nil
+	case "run":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: clify fav run <number|command>")
+		}
+		favorite, err := c.find(strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		return c.run(favorite)
+	case "export":
+		e, err := parseExportFlags("fav", args[1:])
+		if err != nil {
+			return err
+		}
+		favorites := e.options.Favorites(c.favorites.List())
+		return e.write(len(favorites), func(w io.Writer) error {
+			return transfer.WriteFavorites(w, favorites, e.format)
+		})
+	case "import":
+		i, err := parseImportFlags("fav", args[1:])
+		if err != nil {
+			return err
+		}
+		var favorites []models.Favorite
+		if err := i.read(func(r io.Reader) (err error) {
+			favorites, err = transfer.ReadFavorites(r, i.format)
+			return err
+		}); err != nil {
+			return err
+		}
+		result, err := c.favorites.Import(i.options.Favorites(favorites), i.strategy)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported favorites: %s.\n", result)
+		return nil
+	default:
+		return fmt.Errorf("unknown fav command %q; use list, add, rm, run, export or import", args[0])
+	}
+}
+
+func (c *FavCommand) list(args []string) error {
+	flags := flag.NewFlagSet("fav list", flag.ContinueOnError)
+	tag := flags.String("tag", "", "only favorites with this tag")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	search := strings.Join(flags.Args(), " ")
+
+	favorites := c.favorites.List()
+	if len(favorites) == 0 {
+		fmt.Println("No favorites yet. Press S on a result in the TUI, or use clify fav add.")
+		return nil
+	}
+
+	matches := favorites
+	if search != "" {
+		matches = c.favorites.Match(search, config.FavoriteMatchScore)
+	}
+	classifier := safety.NewClassifier()
+	shown := 0
+	for _, favorite := range matches {
+		if *tag != "" && !config.HasTag(favorite, *tag) {
+			continue
+		}
+		shown++
+		fmt.Printf("%3d  %s %s\n", favoriteNumber(favorites, favorite), classifier.GetSafetyIcon(models.SafetyLevel(favorite.SafetyLevel)), favorite.Command)
+		if favorite.Description != "" {
+			fmt.Printf("       %s\n", favorite.Description)
+		}
+		if favorite.Query != "" {
+			fmt.Printf("       for %q\n", favorite.Query)
+		}
+		if len(favorite.Tags) > 0 {
+			fmt.Printf("       #%s\n", strings.Join(favorite.Tags, " #"))
+		}
+	}
+	if shown == 0 {
+		return fmt.Errorf("no favorites match")
+	}
+	return nil
+}
+
+func (c *FavCommand) add(args []string) error {
+	flags := flag.NewFlagSet("fav add", flag.ContinueOnError)
+	query := flags.String("query", "", "the task the command answers")
+	description := flags.String("description", "", "what the command does")
+	tags := flags.String("tags", "", "comma-separated tags")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	command := strings.Join(flags.Args(), " ")
+	if command == "" {
+		return fmt.Errorf("usage: clify fav add [--query Q] [--description D] [--tags a,b] <command>")
+	}
+
+	level := safety.NewClassifier().ClassifyCommand(command)
+	favorite := models.Favorite{
+		Command:     command,
+		Query:       *query,
+		Description: *description,
+		Tags:        config.ParseTags(*tags),
+		SafetyLevel: string(level),
+		Added:       time.Now(),
+	}
+	if err := c.favorites.Add(favorite); err != nil {
+		return err
+	}
+	fmt.Printf("★ Saved %s (%s)\n", command, level)
+	return nil
+}
+
+// find resolves a list number or a command to a favorite
+func (c *FavCommand) find(arg string) (models.Favorite, error) {
+	favorites := c.favorites.List()
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(favorites) {
+			return models.Favorite{}, fmt.Errorf("no favorite %d; see clify fav list", n)
+		}
+		return favorites[n-1], nil
+	}
+	if favorite, found := c.favorites.Get(arg); found {
+		return favorite, nil
+	}
+	return models.Favorite{}, fmt.Errorf("no favorite %q; see clify fav list", arg)
+}
+
+// run executes favorite in the user's shell, asking first if it is
+// dangerous. The command is classified again: favorites.yaml may have been
+// edited by hand or imported.
+func (c *FavCommand) run(favorite models.Favorite) error {
+	fmt.Printf("$ %s\n", favorite.Command)
+	level := safety.Stricter(models.SafetyLevel(favorite.SafetyLevel), safety.NewClassifier().ClassifyCommand(favorite.Command))
+	if level == models.SafetyLevelDangerous {
+		fmt.Print("This command is dangerous. Run it? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			return fmt.Errorf("cancelled")
+		}
+	}
+
+	cmd := exec.Command("sh", "-c", favorite.Command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", favorite.Command)
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// favoriteNumber returns the position of favorite in list, counting from 1
+func favoriteNumber(list []models.Favorite, favorite models.Favorite) int {
+	for i, f := range list {
+		if f.Command == favorite.Command {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/transfer"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HistoryCommand struct{}
+
+func NewHistoryCommand() *HistoryCommand {
+	return &HistoryCommand{}
+}
+
+// Run handles "history [list] [-n N]", "history search <text>",
+// "history delete <number>...|--match <text>", "history clear" and
+// "history export|import".
+// Entries are numbered newest first, as list prints them.
+func (c *HistoryCommand) Run(args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	history := config.NewHistory(cfg.HistorySize)
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"list"}, args...)
+	}
+
+	switch args[0] {
+	case "list":
+		flags := flag.NewFlagSet("history list", flag.ContinueOnError)
+		limit := flags.Int("n", 20, "number of entries to show (0 for all)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		entries := newestFirst(history.Entries())
+		if *limit > 0 && len(entries) > *limit {
+			entries = entries[:*limit]
+		}
+		if len(entries) == 0 {
+			fmt.Println("The history is empty.")
+			return nil
+		}
+		for i, entry := range entries {
+			printHistoryEntry(i+1, entry)
+		}
+		return nil
+	case "search":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: clify history search <text>")
+		}
+		text := strings.Join(args[1:], " ")
+		numbers := historyNumbers(history.Entries())
+		matches := history.Search(text)
+		if len(matches) == 0 {
+			return fmt.Errorf("no history entries match %q", text)
+		}
+		for _, entry := range matches {
+			printHistoryEntry(numbers[entry.ID], entry)
+		}
+		return nil
+	case "delete":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: clify history delete <number>...|--match <text>")
+		}
+		ids, err := c.selectIDs(history, args[1:])
+		if err != nil {
+			return err
+		}
+		removed, err := history.Delete(ids...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %d entries.\n", removed)
+		return nil
+	case "clear":
+		if err := history.Clear(); err != nil {
+			return err
+		}
+		fmt.Println("History cleared.")
+		return nil
+	case "export":
+		e, err := parseExportFlags("history", args[1:])
+		if err != nil {
+			return err
+		}
+		entries := e.options.History(history.Entries())
+		return e.write(len(entries), func(w io.Writer) error {
+			return transfer.WriteHistory(w, entries, e.format)
+		})
+	case "import":
+		i, err := parseImportFlags("history", args[1:])
+		if err != nil {
+			return err
+		}
+		var entries []models.HistoryEntry
+		if err := i.read(func(r io.Reader) (err error) {
+			entries, err = transfer.ReadHistory(r, i.format)
+			return err
+		}); err != nil {
+			return err
+		}
+		result, err := history.Import(i.options.History(entries), i.strategy)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported history: %s.\n", result)
+		return nil
+	default:
+		return fmt.Errorf("unknown history command %q; use list, search, delete, clear, export or import", args[0])
+	}
+}
+
+// selectIDs resolves list numbers, or "--match <text>", to entry IDs
+func (c *HistoryCommand) selectIDs(history *config.History, args []string) ([]string, error) {
+	var ids []string
+	if args[0] == "--match" {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("usage: clify history delete --match <text>")
+		}
+		text := strings.Join(args[1:], " ")
+		for _, entry := range history.Search(text) {
+			ids = append(ids, entry.ID)
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no history entries match %q", text)
+		}
+		return ids, nil
+	}
+
+	entries := newestFirst(history.Entries())
+	for _, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(entries) {
+			return nil, fmt.Errorf("no history entry %s; see clify history list", arg)
+		}
+		ids = append(ids, entries[n-1].ID)
+	}
+	return ids, nil
+}
+
+// newestFirst reverses entries, which the store keeps oldest first
+func newestFirst(entries []models.HistoryEntry) []models.HistoryEntry {
+	reversed := make([]models.HistoryEntry, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = entry
+	}
+	return reversed
+}
+
+// historyNumbers maps entry IDs to the numbers list shows
+func historyNumbers(entries []models.HistoryEntry) map[string]int {
+	numbers := make(map[string]int, len(entries))
+	for i, entry := range newestFirst(entries) {
+		numbers[entry.ID] = i + 1
+	}
+	return numbers
+}
+
+func printHistoryEntry(number int, entry models.HistoryEntry) {
+	fmt.Printf("%4d  %s  %s\n", number, entry.Timestamp.Local().Format(time.DateTime), entry.Query)
+	if entry.Command != "" {
+		fmt.Printf("      → %s\n", entry.Command)
+	}
+	if entry.Cwd != "" {
+		fmt.Printf("      in %s\n", entry.Cwd)
+	}
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"clify/internal/client"
+	"clify/internal/config"
+	"fmt"
+	"strings"
+)
+
+type PromptCommand struct{}
+
+func NewPromptCommand() *PromptCommand {
+	return &PromptCommand{}
+}
+
+// Run handles "prompt show <query>", printing the final rendered prompt
+func (p *PromptCommand) Run(args []string) error {
+	if len(args) < 2 || args[0] != "show" {
+		return fmt.Errorf("usage: clify prompt show <query>")
+	}
+	query := strings.Join(args[1:], " ")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	claudeClient, err := client.NewClaudeClientFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := claudeClient.BuildPrompt(query)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Templates: %s\n", strings.Join(claudeClient.Templates().Sources(), ", "))
+	fmt.Println(strings.Repeat("=", 40))
+	fmt.Println(rendered)
+	return nil
+}
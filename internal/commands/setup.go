@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"clify/internal/client"
 	"clify/internal/config"
+	"clify/internal/credentials"
 	"clify/internal/models"
 	"strings"
 )
@@ -27,7 +28,32 @@ func (s *SetupCommand) Run() error {
 	if err != nil {
 		return fmt.Errorf("failed to load current config: %w", err)
 	}
+	if env := currentConfig.Profiles[currentConfig.Profile].APIKeyEnv; env != "" {
+		fmt.Printf("Profile %s reads the API key from $%s.\n", currentConfig.Profile, env)
+		return nil
+	}
+	if currentConfig.APIKeyCommand != "" {
+		fmt.Println("The API key is read from api_key_command; update it in your password manager.")
+		return nil
+	}
+
+	// Move a plaintext key out of the config file
+	plaintext, err := config.PlaintextAPIKey()
+	if err != nil {
+		return fmt.Errorf("failed to load current config: %w", err)
+	}
+	if plaintext != "" {
+		backend, err := config.StoreAPIKey("", plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to move the API key out of the config file: %w", err)
+		}
+		fmt.Printf("Moved the plaintext API key from config.yaml to %s.\n", describeBackend(backend))
+		return nil
+	}
 
+	if err := config.ResolveAPIKey(currentConfig); err != nil {
+		return err
+	}
 	if currentConfig.APIKey != "" {
 		fmt.Println("API key already configured")
 		fmt.Print("Would you like to update it? (y/N): ")
@@ -55,13 +81,14 @@ func (s *SetupCommand) Run() error {
 	}
 	fmt.Println(" SUCCESS")
 
-	// Update config
-	currentConfig.APIKey = apiKey
-	if err := config.SaveConfig(currentConfig); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	// Store the key outside the config file
+	backend, err := config.StoreAPIKey(currentConfig.Profile, apiKey)
+	if err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
 	}
 
 	fmt.Println()
+	fmt.Printf("API key stored in %s.\n", describeBackend(backend))
 	fmt.Println("Setup completed successfully!")
 	fmt.Println("You can now use clify to query commands.")
 	fmt.Println()
@@ -107,13 +134,19 @@ func (s *SetupCommand) validateAPIKey(apiKey string) error {
 	return client.TestConnection(ctx)
 }
 
-func (s *SetupCommand) IsSetupRequired() bool {
-	config, err := config.LoadConfig()
-	if err != nil {
-		return true
-	}
+// IsSetupRequired reports whether cfg, with its API key resolved, has no
+// way to reach the API
+func (s *SetupCommand) IsSetupRequired(cfg *models.Config) bool {
+	// Replayed fixtures don't need an API key
+	return cfg.APIKey == "" && cfg.Provider != client.ProviderReplay
+}
 
-	return config.APIKey == ""
+// describeBackend says where backend keeps the API key
+func describeBackend(backend credentials.Backend) string {
+	if file, ok := backend.(credentials.EncryptedFile); ok {
+		return "the encrypted file " + file.Path
+	}
+	return "the OS keyring"
 }
 
 func (s *SetupCommand) ShowSetupPrompt() {
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/safety"
+	"clify/internal/snippets"
+	"fmt"
+)
+
+type SnippetsCommand struct{}
+
+func NewSnippetsCommand() *SnippetsCommand {
+	return &SnippetsCommand{}
+}
+
+// Run handles "snippets lint [dir...]", which checks the configured snippet
+// sources unless directories are given
+func (c *SnippetsCommand) Run(args []string) error {
+	if len(args) == 0 || args[0] != "lint" {
+		return fmt.Errorf("usage: clify snippets lint [dir...]")
+	}
+
+	dirs := args[1:]
+	if len(dirs) == 0 {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		dirs = cfg.Snippets.Sources
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no snippet sources; add snippets.sources to the config or name a directory")
+	}
+
+	report := snippets.Lint(dirs)
+
+	classifier := safety.NewClassifier()
+	counts := make(map[models.SafetyLevel]int)
+	for _, snippet := range report.Snippets {
+		counts[snippet.Verdict]++
+		fmt.Printf("%s %s:%d %s\n", classifier.GetSafetyIcon(snippet.Verdict), snippet.File, snippet.Line, snippet.Command)
+	}
+	if len(report.Snippets) > 0 {
+		fmt.Println()
+	}
+	for _, problem := range report.Problems {
+		fmt.Println(problem)
+	}
+
+	fmt.Printf("%d snippets: %d safe, %d warning, %d dangerous; %d errors, %d warnings\n",
+		len(report.Snippets), counts[models.SafetyLevelSafe], counts[models.SafetyLevelWarning], counts[models.SafetyLevelDangerous],
+		report.Errors(), len(report.Problems)-report.Errors())
+	if report.Errors() > 0 {
+		return fmt.Errorf("%d errors", report.Errors())
+	}
+	return nil
+}
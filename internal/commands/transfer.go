@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"clify/internal/config"
+	"clify/internal/transfer"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// exportFlags are the options of "cache|history|fav export"
+type exportFlags struct {
+	options transfer.Options
+	format  transfer.Format
+	output  string
+}
+
+// parseExportFlags reads [--format json|csv] [-o FILE] [--since T]
+// [--until T] [--max-safety LEVEL] [--no-redact]
+func parseExportFlags(name string, args []string) (*exportFlags, error) {
+	flags := flag.NewFlagSet(name+" export", flag.ContinueOnError)
+	format := flags.String("format", "", "json or csv (default: from the file extension, else json)")
+	output := flags.String("o", "", "output file (default: stdout)")
+	since := flags.String("since", "", "only entries from this date, time or duration ago (e.g. 2025-01-31, 7d)")
+	until := flags.String("until", "", "only entries up to this date, time or duration ago")
+	maxSafety := flags.String("max-safety", "", "leave out commands above this level (safe, warning)")
+	noRedact := flags.Bool("no-redact", false, "keep secrets such as API keys and passwords")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	e := &exportFlags{output: *output}
+	var err error
+	if e.format, err = transfer.DetectFormat(*format, *output); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if e.options.Since, err = transfer.ParseTime(*since, now); err != nil {
+		return nil, err
+	}
+	if e.options.Until, err = transfer.ParseUntil(*until, now); err != nil {
+		return nil, err
+	}
+	if e.options.MaxSafety, err = transfer.ParseSafety(*maxSafety); err != nil {
+		return nil, err
+	}
+	e.options.Redact = !*noRedact
+	return e, nil
+}
+
+// write creates the output, or uses stdout, and reports the count on stderr
+func (e *exportFlags) write(count int, writeTo func(io.Writer) error) error {
+	if e.output == "" || e.output == "-" {
+		return writeTo(os.Stdout)
+	}
+
+	f, err := os.OpenFile(e.output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := writeTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d entries to %s\n", count, e.output)
+	return nil
+}
+
+// importFlags are the options of "cache|history|fav import"
+type importFlags struct {
+	options  transfer.Options
+	format   transfer.Format
+	strategy config.MergeStrategy
+	input    string
+}
+
+// parseImportFlags reads FILE [--format json|csv] [--strategy newest|existing]
+// [--since T] [--until T] [--max-safety LEVEL] [--redact]; FILE "-" is stdin
+func parseImportFlags(name string, args []string) (*importFlags, error) {
+	flags := flag.NewFlagSet(name+" import", flag.ContinueOnError)
+	format := flags.String("format", "", "json or csv (default: from the file extension, else json)")
+	strategy := flags.String("strategy", "newest", "on conflicts keep the newest entry or the existing one (newest, existing)")
+	since := flags.String("since", "", "only entries from this date, time or duration ago")
+	until := flags.String("until", "", "only entries up to this date, time or duration ago")
+	maxSafety := flags.String("max-safety", "", "leave out commands above this level (safe, warning)")
+	redactSecrets := flags.Bool("redact", false, "replace secrets such as API keys and passwords")
+
+	// Allow the file before or after the flags
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) != 1 {
+		return nil, fmt.Errorf("usage: clify %s import FILE [--format json|csv] [--strategy newest|existing]", name)
+	}
+
+	i := &importFlags{input: positional[0]}
+	var err error
+	if i.format, err = transfer.DetectFormat(*format, i.input); err != nil {
+		return nil, err
+	}
+	if i.strategy, err = config.ParseMergeStrategy(*strategy); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if i.options.Since, err = transfer.ParseTime(*since, now); err != nil {
+		return nil, err
+	}
+	if i.options.Until, err = transfer.ParseUntil(*until, now); err != nil {
+		return nil, err
+	}
+	if i.options.MaxSafety, err = transfer.ParseSafety(*maxSafety); err != nil {
+		return nil, err
+	}
+	i.options.Redact = *redactSecrets
+	return i, nil
+}
+
+// read opens the input, or stdin for "-"
+func (i *importFlags) read(readFrom func(io.Reader) error) error {
+	if i.input == "-" {
+		return readFrom(os.Stdin)
+	}
+	f, err := os.Open(i.input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readFrom(f)
+}
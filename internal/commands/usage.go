@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"clify/internal/config"
+	"clify/internal/models"
+	"fmt"
+	"time"
+)
+
+type UsageCommand struct {
+	tracker *config.UsageTracker
+}
+
+func NewUsageCommand() *UsageCommand {
+	return &UsageCommand{
+		tracker: config.NewUsageTracker(),
+	}
+}
+
+// Run prints daily and monthly token usage and cost
+func (u *UsageCommand) Run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Println("clify Usage")
+	fmt.Println("===========")
+	fmt.Println()
+
+	monthly := u.tracker.Monthly()
+	if len(monthly) == 0 {
+		fmt.Println("No API usage recorded yet.")
+		return nil
+	}
+
+	fmt.Println("Daily (last 30 days):")
+	u.printTotals(lastN(u.tracker.Daily(), 30))
+	fmt.Println()
+
+	fmt.Println("Monthly:")
+	u.printTotals(monthly)
+	fmt.Println()
+
+	u.tracker.SetProfile(cfg.Profile)
+	u.printBudget(cfg.Profile, cfg.Budget)
+	return nil
+}
+
+func (u *UsageCommand) printTotals(totals []config.UsageTotal) {
+	fmt.Printf("  %-10s %8s %10s %10s %10s\n", "Period", "Queries", "Input", "Output", "Cost")
+	for _, total := range totals {
+		fmt.Printf("  %-10s %8d %10d %10d %10s\n", total.Period, total.Queries, total.InputTokens, total.OutputTokens, fmt.Sprintf("$%.4f", total.CostUSD))
+	}
+}
+
+func (u *UsageCommand) printBudget(profile string, budget models.BudgetConfig) {
+	spent := u.tracker.MonthToDate(time.Now())
+	if profile != "" {
+		fmt.Printf("Profile: %s\n", profile)
+	}
+	if budget.Monthly <= 0 {
+		fmt.Printf("This month: $%.4f (no budget set)\n", spent)
+		return
+	}
+
+	action := budget.Action
+	if action == "" {
+		action = config.BudgetActionWarn
+	}
+	fmt.Printf("This month: $%.4f of $%.2f budget (%.0f%%, action: %s)\n", spent, budget.Monthly, spent/budget.Monthly*100, action)
+	if model := u.tracker.UnpricedModel(time.Now()); model != "" {
+		fmt.Printf("The price of %s is unknown, so its calls are not counted.\n", model)
+	}
+	if spent >= budget.Monthly {
+		fmt.Println("Budget exceeded.")
+	}
+}
+
+func lastN(totals []config.UsageTotal, n int) []config.UsageTotal {
+	if len(totals) > n {
+		return totals[len(totals)-n:]
+	}
+	return totals
+}
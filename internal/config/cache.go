@@ -1,23 +1,138 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"clify/internal/fuzzy"
 	"clify/internal/models"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
 	DefaultCacheDir  = ".clify"
 	DefaultCacheFile = "cache.json"
-	CacheExpiry      = 24 * time.Hour
+
+	// CacheVersion is the format of cache.json. Version 1 files are a
+	// plain map from query key to entry.
+	CacheVersion = 2
+
+	// DefaultSimilarityThreshold is the lowest score at which a past query
+	// is offered in place of a new one
+	DefaultSimilarityThreshold = 0.8
 )
 
+// CachePolicy bounds how long entries live and how large the cache grows.
+// Least recently used entries are evicted first; pinned entries never are.
+// Zero values mean no limit.
+type CachePolicy struct {
+	TTL        time.Duration
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// DefaultCachePolicy applies until SetPolicy is called
+var DefaultCachePolicy = CachePolicy{
+	TTL:        24 * time.Hour,
+	MaxEntries: 1000,
+	MaxBytes:   10 << 20,
+}
+
+// ParseCachePolicy converts the cache section of the config file
+func ParseCachePolicy(cfg models.CacheConfig) (CachePolicy, error) {
+	policy := CachePolicy{MaxEntries: cfg.MaxEntries}
+
+	switch ttl := strings.TrimSpace(cfg.TTL); ttl {
+	case "":
+		policy.TTL = DefaultCachePolicy.TTL
+	case "never", "0":
+		policy.TTL = 0
+	default:
+		d, err := ParseDuration(ttl)
+		if err != nil {
+			return policy, fmt.Errorf("invalid cache ttl %q: %w", cfg.TTL, err)
+		}
+		policy.TTL = d
+	}
+
+	size, err := parseSize(cfg.MaxSize)
+	if err != nil {
+		return policy, fmt.Errorf("invalid cache max_size %q: %w", cfg.MaxSize, err)
+	}
+	policy.MaxBytes = size
+	return policy, nil
+}
+
+// ParseDuration extends time.ParseDuration with whole days, e.g. "7d"
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("not a number of days")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d < 0 {
+		return 0, fmt.Errorf("negative duration")
+	}
+	return d, err
+}
+
+// parseSize reads sizes such as "512KB" or "10MB"; empty means unlimited
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, multiplier = strings.TrimSpace(number), unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("not a size")
+	}
+	return n * multiplier, nil
+}
+
+// cacheFile is the on-disk layout of cache.json
+type cacheFile struct {
+	Version int                 `json:"version"`
+	Entries []models.CacheEntry `json:"entries"`
+}
+
+// CacheManager stores responses in memory and in cache.json. It is safe for
+// concurrent use, and several clify processes may share the file: saves take
+// a file lock and merge entries written by others since the last load.
 type CacheManager struct {
-	filePath string
-	cache    map[string]models.CacheEntry
+	mu         sync.Mutex
+	filePath   string
+	policy     CachePolicy
+	cache      map[string]models.CacheEntry
+	removed    map[string]time.Time // deleted keys and the timestamps they had
+	pinChanged map[string]bool      // keys pinned or unpinned since the last save
+	clearedAt  time.Time
+	touched    bool  // hits recorded in memory since the last save
+	readOnly   error // why the file must not be overwritten, e.g. it has a newer format
 }
 
 func NewCacheManager() *CacheManager {
@@ -31,91 +146,665 @@ func NewCacheManager() *CacheManager {
 
 	// Ensure cache directory exists
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		fmt.Printf("Warning: failed to create cache directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: failed to create cache directory: %v\n", err)
 	}
 
 	cm := &CacheManager{
-		filePath: filePath,
-		cache:    make(map[string]models.CacheEntry),
+		filePath:   filePath,
+		policy:     DefaultCachePolicy,
+		cache:      make(map[string]models.CacheEntry),
+		removed:    make(map[string]time.Time),
+		pinChanged: make(map[string]bool),
 	}
 
 	if err := cm.loadCache(); err != nil {
-		fmt.Printf("Warning: failed to load cache: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: failed to load cache: %v\n", err)
 	}
 	return cm
 }
 
+// NewCacheManagerFromConfig creates a cache manager with the configured limits
+func NewCacheManagerFromConfig(cfg *models.Config) (*CacheManager, error) {
+	policy, err := ParseCachePolicy(cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
+	cm := NewCacheManager()
+	cm.SetPolicy(policy)
+	return cm, nil
+}
+
 func (cm *CacheManager) Get(query string) (string, bool) {
-	entry, exists := cm.cache[query]
+	return cm.GetScoped(models.CacheScope{}, query)
+}
+
+// GetScoped looks up query within scope. The empty scope is the one used by Get.
+func (cm *CacheManager) GetScoped(scope models.CacheScope, query string) (string, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	key := scopedKey(scope, query)
+	entry, exists := cm.cache[key]
 	if !exists {
 		return "", false
 	}
 
 	// Check if cache entry is expired
-	if time.Since(entry.Timestamp) > CacheExpiry {
-		delete(cm.cache, query)
+	if cm.expired(entry) {
+		cm.remove(key)
 		if err := cm.saveCache(); err != nil {
-			fmt.Printf("Warning: failed to save cache after expiry cleanup: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Warning: failed to save cache after expiry cleanup: %v\n", err)
 		}
 		return "", false
 	}
 
+	// Record the hit for LRU eviction. It is saved with the next write, or
+	// by Flush.
+	entry.LastUsed = time.Now()
+	cm.cache[key] = entry
+	cm.touched = true
+
+	return entry.Response, true
+}
+
+// Flush saves hits recorded since the last write, if any
+func (cm *CacheManager) Flush() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if !cm.touched {
+		return nil
+	}
+	return cm.saveCache()
+}
+
+// Peek returns the cached response for query without counting it as a use,
+// e.g. for a preview
+func (cm *CacheManager) Peek(scope models.CacheScope, query string) (string, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	entry, exists := cm.cache[scopedKey(scope, query)]
+	if !exists || cm.expired(entry) {
+		return "", false
+	}
 	return entry.Response, true
 }
 
+// SetPolicy replaces the expiry and size limits. They are enforced on the
+// next save or Compact.
+func (cm *CacheManager) SetPolicy(policy CachePolicy) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.policy = policy
+}
+
+// Expired reports whether entry is past the TTL and not pinned
+func (cm *CacheManager) Expired(entry models.CacheEntry) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.expired(entry)
+}
+
+func (cm *CacheManager) expired(entry models.CacheEntry) bool {
+	return !entry.Pinned && cm.policy.TTL > 0 && time.Since(entry.Timestamp) > cm.policy.TTL
+}
+
+// Compact drops expired entries and evicts least recently used ones until
+// the cache is within its limits, then rewrites the file if anything changed
+func (cm *CacheManager) Compact() (int, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	unlock, err := lockFile(cm.filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	cm.mergeFile()
+	evicted := cm.compact()
+	if evicted == 0 && !cm.touched {
+		return 0, nil
+	}
+	return evicted, cm.writeCache()
+}
+
+// compact applies the policy to the in-memory entries and returns how many
+// it removed
+func (cm *CacheManager) compact() int {
+	evicted := 0
+	var candidates []string
+	for key, entry := range cm.cache {
+		switch {
+		case cm.expired(entry):
+			cm.remove(key)
+			evicted++
+		case !entry.Pinned:
+			candidates = append(candidates, key)
+		}
+	}
+
+	// Least recently used first
+	sort.Slice(candidates, func(i, j int) bool {
+		return lastUsed(cm.cache[candidates[i]]).Before(lastUsed(cm.cache[candidates[j]]))
+	})
+
+	if limit := cm.policy.MaxEntries; limit > 0 {
+		for len(cm.cache) > limit && len(candidates) > 0 {
+			cm.remove(candidates[0])
+			candidates = candidates[1:]
+			evicted++
+		}
+	}
+
+	if limit := cm.policy.MaxBytes; limit > 0 {
+		sizes := make(map[string]int64, len(cm.cache))
+		var total int64
+		for key, entry := range cm.cache {
+			sizes[key] = entrySize(entry)
+			total += sizes[key]
+		}
+		for total > limit && len(candidates) > 0 {
+			total -= sizes[candidates[0]]
+			cm.remove(candidates[0])
+			candidates = candidates[1:]
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// lastUsed is when entry was last stored or served
+func lastUsed(entry models.CacheEntry) time.Time {
+	if entry.LastUsed.After(entry.Timestamp) {
+		return entry.LastUsed
+	}
+	return entry.Timestamp
+}
+
+// entrySize approximates the bytes entry takes in the indented cache file
+func entrySize(entry models.CacheEntry) int64 {
+	data, _ := json.Marshal(entry)
+	return int64(len(data)) + 64
+}
+
+// Pin marks the entry for query within scope so that it never expires or
+// is evicted, or clears the mark
+func (cm *CacheManager) Pin(scope models.CacheScope, query string, pinned bool) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	key := scopedKey(scope, query)
+	if _, ok := cm.cache[key]; !ok {
+		return fmt.Errorf("%q is not cached", query)
+	}
+	cm.setPinned(key, pinned)
+	return cm.saveCache()
+}
+
+// PinQuery pins or unpins the entries for query in every scope and returns
+// how many it changed
+func (cm *CacheManager) PinQuery(query string, pinned bool) (int, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	normalized := NormalizeQuery(query)
+	changed := 0
+	for key, entry := range cm.cache {
+		if NormalizeQuery(entry.Query) == normalized && entry.Pinned != pinned {
+			cm.setPinned(key, pinned)
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, cm.saveCache()
+}
+
+// IsPinned reports whether the entry for query within scope is pinned
+func (cm *CacheManager) IsPinned(scope models.CacheScope, query string) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.cache[scopedKey(scope, query)].Pinned
+}
+
+func (cm *CacheManager) setPinned(key string, pinned bool) {
+	entry := cm.cache[key]
+	entry.Pinned = pinned
+	cm.cache[key] = entry
+	cm.pinChanged[key] = true
+}
+
+// Entries returns all cached entries, including expired ones
+func (cm *CacheManager) Entries() []models.CacheEntry {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.entries()
+}
+
+func (cm *CacheManager) entries() []models.CacheEntry {
+	entries := make([]models.CacheEntry, 0, len(cm.cache))
+	for _, entry := range cm.cache {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// FindSimilar returns the cached query within scope that is most similar to
+// query, scoring at least threshold. Queries that normalize to the same key
+// are not returned, as Get already finds them.
+func (cm *CacheManager) FindSimilar(scope models.CacheScope, query string, threshold float64) (string, float64, bool) {
+	if threshold <= 0 {
+		return "", 0, false
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	key := scopedKey(scope, query)
+	best, bestScore := "", 0.0
+	for _, entry := range cm.cache {
+		if entry.CacheScope != scope || scopedKey(scope, entry.Query) == key || cm.expired(entry) {
+			continue
+		}
+		if score := fuzzy.Similarity(query, entry.Query); score > bestScore {
+			best, bestScore = entry.Query, score
+		}
+	}
+	if bestScore < threshold {
+		return "", 0, false
+	}
+	return best, bestScore, true
+}
+
 func (cm *CacheManager) Set(query, response string) error {
-	cm.cache[query] = models.CacheEntry{
-		Query:     query,
-		Response:  response,
-		Timestamp: time.Now(),
+	return cm.SetScoped(models.CacheScope{}, query, response)
+}
+
+// SetScoped stores response for query within scope
+func (cm *CacheManager) SetScoped(scope models.CacheScope, query, response string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	key := scopedKey(scope, query)
+	delete(cm.removed, key)
+	cm.cache[key] = models.CacheEntry{
+		CacheScope: scope,
+		Query:      query,
+		Response:   response,
+		Timestamp:  time.Now(),
+		Pinned:     cm.cache[key].Pinned,
 	}
 
 	return cm.saveCache()
 }
 
+// loadCache reads the cache file. A corrupt file is moved aside and as many
+// entries as possible are recovered from it and from the last good copy.
 func (cm *CacheManager) loadCache() error {
-	data, err := os.ReadFile(cm.filePath)
+	unlock, err := lockFile(cm.filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	raw, err := os.ReadFile(cm.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // Cache file doesn't exist yet, that's okay
 		}
 		return fmt.Errorf("failed to read cache file: %w", err)
 	}
+	data, err := unseal(raw)
+	if err != nil {
+		// Without the key the file cannot be told from a corrupt one, so
+		// leave it alone
+		cm.readOnly = fmt.Errorf("cannot read cache file: %w", err)
+		return cm.readOnly
+	}
+
+	entries, migrated, err := parseCache(data)
+	switch {
+	case errors.Is(err, errNewerCache):
+		cm.readOnly = err
+		return err
+	case err != nil:
+		return cm.recoverCache(data, err)
+	}
+
+	for _, entry := range entries {
+		cm.add(entry)
+	}
+	if migrated {
+		return cm.writeCache()
+	}
+
+	// Keep a copy of the last file that loaded cleanly for recovery
+	if err := writeFileAtomic(cm.filePath+".bak", raw, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to back up cache: %v\n", err)
+	}
+	return nil
+}
+
+// recoverCache salvages the entries before the damage in data, adds those of
+// the backup and rewrites the cache file. The damaged file is kept as
+// cache.json.corrupt.
+func (cm *CacheManager) recoverCache(data []byte, cause error) error {
+	corruptPath := cm.filePath + ".corrupt"
+	if err := os.Rename(cm.filePath, corruptPath); err != nil {
+		return fmt.Errorf("cache file is corrupt (%v) and could not be moved aside: %w", cause, err)
+	}
+
+	salvaged := salvageEntries(data)
+	for _, entry := range salvaged {
+		cm.add(entry)
+	}
+	if backup, err := readSealed(cm.filePath + ".bak"); err == nil {
+		if entries, _, err := parseCache(backup); err == nil {
+			for _, entry := range entries {
+				cm.add(entry)
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: cache file was corrupt (%v); recovered %d entries, damaged file kept at %s\n", cause, len(cm.cache), corruptPath)
+	return cm.writeCache()
+}
+
+// errNewerCache means the cache file was written by a newer clify
+var errNewerCache = errors.New("cache file is newer than this version of clify")
+
+// parseCache decodes a cache file of any version. migrated reports that it
+// was a version 1 file, which the caller should rewrite.
+func parseCache(data []byte) (entries []models.CacheEntry, migrated bool, err error) {
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err == nil && file.Version != 0 {
+		if file.Version > CacheVersion {
+			return nil, false, fmt.Errorf("%w (version %d, supported %d)", errNewerCache, file.Version, CacheVersion)
+		}
+		return file.Entries, false, nil
+	}
+
+	entries, err = parseLegacyCache(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return entries, true, nil
+}
+
+// parseLegacyCache converts a version 1 cache, a map keyed by "[target] query".
+// Migrated entries have no model, prompt or schema hash, so they are never
+// served again, but they remain available to similarity search and offline
+// answers.
+func parseLegacyCache(data []byte) ([]models.CacheEntry, error) {
+	var legacy map[string]models.CacheEntry
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cache data: %w", err)
+	}
+
+	entries := make([]models.CacheEntry, 0, len(legacy))
+	for key, entry := range legacy {
+		entry.CacheScope = legacyScope(keyScope(key))
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
 
-	if err := json.Unmarshal(data, &cm.cache); err != nil {
-		return fmt.Errorf("failed to unmarshal cache data: %w", err)
+// salvageEntries decodes the entries of a current-format cache file up to
+// the first damaged one, e.g. in a file truncated by a full disk
+func salvageEntries(data []byte) []models.CacheEntry {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
 	}
 
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		if key != "entries" {
+			var skip json.RawMessage
+			if dec.Decode(&skip) != nil {
+				return nil
+			}
+			continue
+		}
+
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return nil
+		}
+		var entries []models.CacheEntry
+		for dec.More() {
+			var entry models.CacheEntry
+			if err := dec.Decode(&entry); err != nil {
+				break
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
 	return nil
 }
 
+// add stores entry, keeping the newest when two entries share a key
+func (cm *CacheManager) add(entry models.CacheEntry) {
+	key := scopedKey(entry.CacheScope, entry.Query)
+	if existing, ok := cm.cache[key]; !ok || entry.Timestamp.After(existing.Timestamp) {
+		cm.cache[key] = entry
+	}
+}
+
+// remove deletes key and remembers it, so that a save does not bring the
+// entry back from the file
+func (cm *CacheManager) remove(key string) {
+	if entry, ok := cm.cache[key]; ok {
+		cm.removed[key] = entry.Timestamp
+		delete(cm.cache, key)
+	}
+}
+
+// saveCache merges entries other processes saved since the last load and
+// writes the result. The caller holds cm.mu.
 func (cm *CacheManager) saveCache() error {
-	data, err := json.MarshalIndent(cm.cache, "", "  ")
+	unlock, err := lockFile(cm.filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cm.mergeFile()
+	cm.compact()
+	return cm.writeCache()
+}
+
+// mergeFile merges the entries currently in the file. The caller holds the
+// file lock.
+func (cm *CacheManager) mergeFile() {
+	data, err := readSealed(cm.filePath)
+	if err != nil {
+		return
+	}
+	entries, _, err := parseCache(data)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		cm.merge(entry)
+	}
+}
+
+// merge combines an entry read from the file with the in-memory one. Entries
+// this process deleted or cleared stay deleted, and its pin changes win.
+func (cm *CacheManager) merge(entry models.CacheEntry) {
+	if entry.Timestamp.Before(cm.clearedAt) {
+		return
+	}
+	key := scopedKey(entry.CacheScope, entry.Query)
+	if removedAt, ok := cm.removed[key]; ok && !entry.Timestamp.After(removedAt) {
+		return
+	}
+
+	existing, ok := cm.cache[key]
+	if !ok {
+		cm.cache[key] = entry
+		return
+	}
+	if entry.Timestamp.Before(existing.Timestamp) {
+		return
+	}
+
+	merged := entry
+	if entry.Timestamp.Equal(existing.Timestamp) && existing.LastUsed.After(entry.LastUsed) {
+		merged.LastUsed = existing.LastUsed
+	}
+	if cm.pinChanged[key] {
+		merged.Pinned = existing.Pinned
+	}
+	cm.cache[key] = merged
+}
+
+// writeCache replaces the cache file with the in-memory entries. The caller
+// holds the file lock.
+func (cm *CacheManager) writeCache() error {
+	if cm.readOnly != nil {
+		return cm.readOnly
+	}
+
+	file := cacheFile{Version: CacheVersion, Entries: cm.entries()}
+	sort.Slice(file.Entries, func(i, j int) bool {
+		return file.Entries[i].Timestamp.Before(file.Entries[j].Timestamp)
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal cache: %w", err)
 	}
+	if data, err = seal(data); err != nil {
+		return err
+	}
 
-	if err := os.WriteFile(cm.filePath, data, 0644); err != nil {
+	if err := writeFileAtomic(cm.filePath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
-
+	clear(cm.removed)
+	clear(cm.pinChanged)
+	cm.touched = false
 	return nil
 }
 
+// Import merges entries into the cache. Imported entries the cache policy
+// would drop at once, such as those older than the ttl, are counted as
+// dropped.
+func (cm *CacheManager) Import(entries []models.CacheEntry, strategy MergeStrategy) (ImportResult, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	unlock, err := lockFile(cm.filePath)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer unlock()
+
+	cm.mergeFile()
+	var result ImportResult
+	imported := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		key := scopedKey(entry.CacheScope, entry.Query)
+		existing, exists := cm.cache[key]
+		switch {
+		case !exists:
+			result.Added++
+		case strategy.replaces(entry.Timestamp, existing.Timestamp):
+			result.Replaced++
+		default:
+			result.Kept++
+			continue
+		}
+		cm.cache[key] = entry
+		delete(cm.removed, key)
+		imported[key] = true
+	}
+
+	cm.compact()
+	for key := range imported {
+		if _, ok := cm.cache[key]; !ok {
+			result.Dropped++
+		}
+	}
+	return result, cm.writeCache()
+}
+
 func (cm *CacheManager) Clear() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
 	cm.cache = make(map[string]models.CacheEntry)
+	cm.clearedAt = time.Now()
 	return cm.saveCache()
 }
 
 func (cm *CacheManager) Size() int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
 	return len(cm.cache)
 }
 
-// GetSearchHistory returns a slice of recent search queries
-func (cm *CacheManager) GetSearchHistory() []string {
-	queries := make([]string, 0, len(cm.cache))
-	for query := range cm.cache {
-		queries = append(queries, query)
+// scopedKey combines scope with the normalized query so that answers for
+// different scopes never collide
+func scopedKey(scope models.CacheScope, query string) string {
+	return scope.String() + "\x00" + NormalizeQuery(query)
+}
+
+// keyScope returns the scope of a version 1 key, e.g. "workflow linux/bash"
+func keyScope(key string) string {
+	if !strings.HasPrefix(key, "[") {
+		return ""
+	}
+	end := strings.Index(key, "] ")
+	if end < 0 {
+		return ""
+	}
+	return key[1:end]
+}
+
+// legacyScope maps a version 1 scope onto its dimensions. Version 1 scopes
+// joined the overridden target fields, so a two-part target is read as
+// OS and shell; an unscoped entry was made for the local OS.
+func legacyScope(scope string) models.CacheScope {
+	var result models.CacheScope
+	if scope == "workflow" || strings.HasPrefix(scope, "workflow ") {
+		result.Kind = "workflow"
+		scope = strings.TrimSpace(strings.TrimPrefix(scope, "workflow"))
+	}
+
+	parts := strings.Split(scope, "/")
+	result.OS = parts[0]
+	if len(parts) > 1 {
+		result.Shell = parts[1]
+	}
+	if len(parts) > 2 {
+		result.Distro = parts[2]
+	}
+	if result.OS == "" {
+		result.OS = runtime.GOOS
+	}
+	return result
+}
+
+// NormalizeQuery lower-cases query, collapses whitespace and drops
+// punctuation ending the sentence, so that "Find all .txt files!" and
+// "find all .txt files" share a cache entry. Other symbols are kept, as
+// "find files > 1G" and "find files < 1G" ask different things.
+func NormalizeQuery(query string) string {
+	words := strings.Fields(strings.ToLower(query))
+	if n := len(words); n > 0 {
+		// "cd .." ends in dots that are not punctuation
+		last := strings.TrimRight(words[n-1], ".?!,;:")
+		if r, _ := utf8.DecodeLastRuneInString(last); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			words[n-1] = last
+		}
 	}
-	return queries
+	return strings.Join(words, " ")
 }
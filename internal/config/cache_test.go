@@ -0,0 +1,428 @@
+package config
+
+import (
+	"clify/internal/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T) *CacheManager {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return NewCacheManager()
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := map[string]string{
+		"Find all .txt files":       "find all .txt files",
+		"  find   all txt files!  ": "find all txt files",
+		"what's using port 8080?":   "what's using port 8080",
+		"go up with cd ..":          "go up with cd ..",
+		"list files in ~/src.":      "list files in ~/src",
+		"":                          "",
+	}
+	for query, want := range tests {
+		if got := NormalizeQuery(query); got != want {
+			t.Errorf("NormalizeQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	// Symbols change the meaning
+	for _, pair := range [][2]string{
+		{"find files > 1G", "find files < 1G"},
+		{"compile c++ file", "compile c file"},
+		{"list #channels", "list channels"},
+		{"delete ~/tmp", "delete /tmp"},
+		{"count *.go files", "count go files"},
+		{"show git diff --staged", "show git diff staged"},
+	} {
+		if NormalizeQuery(pair[0]) == NormalizeQuery(pair[1]) {
+			t.Errorf("NormalizeQuery(%q) and NormalizeQuery(%q) collide", pair[0], pair[1])
+		}
+	}
+}
+
+func TestGetNormalizesQuery(t *testing.T) {
+	cache := newTestCache(t)
+	linux := models.CacheScope{Model: "m", OS: "linux", Prompt: "p1", Schema: "s1"}
+	if err := cache.SetScoped(linux, "find all txt files", "response"); err != nil {
+		t.Fatalf("SetScoped() error = %v", err)
+	}
+
+	if got, found := cache.GetScoped(linux, "Find  all TXT files?"); !found || got != "response" {
+		t.Errorf("GetScoped() = %q, %v, want the stored response", got, found)
+	}
+
+	// Any other dimension is a different answer
+	for _, scope := range []models.CacheScope{
+		{Model: "m", OS: "darwin", Prompt: "p1", Schema: "s1"},
+		{Model: "other", OS: "linux", Prompt: "p1", Schema: "s1"},
+		{Model: "m", OS: "linux", Shell: "fish", Prompt: "p1", Schema: "s1"},
+		{Model: "m", OS: "linux", Prompt: "p2", Schema: "s1"},
+		{Model: "m", OS: "linux", Prompt: "p1", Schema: "s2"},
+		{Kind: "workflow", Model: "m", OS: "linux", Prompt: "p1", Schema: "s1"},
+	} {
+		if _, found := cache.GetScoped(scope, "find all txt files"); found {
+			t.Errorf("GetScoped(%+v) found an entry from another scope", scope)
+		}
+	}
+}
+
+func TestMigrateVersion1Cache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	now := time.Now()
+	legacy := map[string]models.CacheEntry{
+		"Find all txt files!":             {Query: "Find all txt files!", Response: "old", Timestamp: now.Add(-time.Hour)},
+		"find all txt files":              {Query: "find all txt files", Response: "new", Timestamp: now},
+		"[linux/bash] list files":         {Query: "list files", Response: "scoped", Timestamp: now},
+		"[workflow darwin] set up a venv": {Query: "set up a venv", Response: "steps", Timestamp: now},
+	}
+	data, _ := json.Marshal(legacy)
+	path := filepath.Join(home, DefaultCacheDir, DefaultCacheFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewCacheManager()
+	if cache.Size() != 3 {
+		t.Errorf("Size() = %d, want 3 after merging equivalent keys", cache.Size())
+	}
+
+	scopes := make(map[string]models.CacheEntry)
+	for _, entry := range cache.Entries() {
+		scopes[entry.Query] = entry
+	}
+	if got := scopes["find all txt files"]; got.OS != runtime.GOOS || got.Response != "new" {
+		t.Errorf("unscoped entry = %+v, want the newest entry for the local OS", got)
+	}
+	if got := scopes["list files"].CacheScope; got != (models.CacheScope{OS: "linux", Shell: "bash"}) {
+		t.Errorf("target entry scope = %+v, want linux/bash", got)
+	}
+	if got := scopes["set up a venv"].CacheScope; got != (models.CacheScope{Kind: "workflow", OS: "darwin"}) {
+		t.Errorf("workflow entry scope = %+v, want a darwin workflow", got)
+	}
+
+	// The file is rewritten in the current format
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != CacheVersion || len(file.Entries) != 3 {
+		t.Errorf("migrated file = %s, want version %d with 3 entries", data, CacheVersion)
+	}
+	if reloaded := NewCacheManager(); reloaded.Size() != 3 {
+		t.Errorf("reloaded Size() = %d, want 3", reloaded.Size())
+	}
+}
+
+func TestFindSimilar(t *testing.T) {
+	cache := newTestCache(t)
+	cache.Set("find all txt files in this directory", "a")
+	cache.Set("show disk usage", "b")
+
+	similar, score, found := cache.FindSimilar(models.CacheScope{}, "find txt files in directory", DefaultSimilarityThreshold)
+	if !found || similar != "find all txt files in this directory" {
+		t.Errorf("FindSimilar() = %q, %.2f, %v, want the txt query", similar, score, found)
+	}
+
+	if _, _, found := cache.FindSimilar(models.CacheScope{}, "Find all TXT files in this directory!", DefaultSimilarityThreshold); found {
+		t.Error("FindSimilar() returned a query that Get already matches")
+	}
+	if _, _, found := cache.FindSimilar(models.CacheScope{}, "kill a process", DefaultSimilarityThreshold); found {
+		t.Error("FindSimilar() matched an unrelated query")
+	}
+	if _, _, found := cache.FindSimilar(models.CacheScope{}, "find txt files in directory", 0); found {
+		t.Error("FindSimilar() with threshold 0 should be disabled")
+	}
+}
+
+func TestConcurrentSet(t *testing.T) {
+	cache := newTestCache(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := cache.Set(fmt.Sprintf("query %d", i), "response"); err != nil {
+				t.Errorf("Set() error = %v", err)
+			}
+			cache.Get(fmt.Sprintf("query %d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	if reloaded := NewCacheManager(); reloaded.Size() != 20 {
+		t.Errorf("reloaded Size() = %d, want 20", reloaded.Size())
+	}
+}
+
+func TestSaveMergesOtherProcesses(t *testing.T) {
+	first := newTestCache(t)
+	second := NewCacheManager()
+
+	first.Set("list files", "a")
+	second.Set("show disk usage", "b")
+	first.Set("kill a process", "c")
+
+	if reloaded := NewCacheManager(); reloaded.Size() != 3 {
+		t.Errorf("reloaded Size() = %d, want entries of both processes", reloaded.Size())
+	}
+
+	// A clear is not undone by entries the other process still holds
+	first.Clear()
+	if reloaded := NewCacheManager(); reloaded.Size() != 0 {
+		t.Errorf("Size() after Clear() = %d, want 0", reloaded.Size())
+	}
+	second.Set("new query", "d")
+	if reloaded := NewCacheManager(); reloaded.Size() != 3 {
+		t.Errorf("Size() = %d, want the other process's entries to be kept on its own save", reloaded.Size())
+	}
+}
+
+func TestRecoverCorruptCache(t *testing.T) {
+	cache := newTestCache(t)
+	cache.Set("list files", "a")
+	cache.Set("show disk usage", "b")
+	cache.Set("kill a process", "c")
+
+	// Truncate the file inside the last entry
+	path := cache.filePath
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cut := strings.LastIndex(string(data), `"response"`)
+	if err := os.WriteFile(path, data[:cut], 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := NewCacheManager()
+	if recovered.Size() != 2 {
+		t.Errorf("Size() = %d, want the 2 intact entries", recovered.Size())
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("damaged file was not kept: %v", err)
+	}
+	if reloaded := NewCacheManager(); reloaded.Size() != 2 {
+		t.Errorf("rewritten cache Size() = %d, want 2", reloaded.Size())
+	}
+
+	// Garbage recovers from the backup of the last clean load
+	if err := os.WriteFile(path, []byte("\x00\x00garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if recovered := NewCacheManager(); recovered.Size() != 2 {
+		t.Errorf("Size() = %d, want the entries of the backup", recovered.Size())
+	}
+}
+
+func TestNewerCacheIsNotOverwritten(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, DefaultCacheDir, DefaultCacheFile)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	newer := []byte(`{"version": 99, "entries": []}`)
+	if err := os.WriteFile(path, newer, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewCacheManager()
+	if err := cache.Set("list files", "a"); err == nil {
+		t.Error("Set() should refuse to overwrite a newer cache format")
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(newer) {
+		t.Errorf("newer cache file was modified: %s", data)
+	}
+}
+
+func TestParseCachePolicy(t *testing.T) {
+	tests := []struct {
+		cfg     models.CacheConfig
+		want    CachePolicy
+		wantErr bool
+	}{
+		{models.CacheConfig{}, CachePolicy{TTL: 24 * time.Hour}, false},
+		{models.CacheConfig{TTL: "never", MaxEntries: 50, MaxSize: "512KB"}, CachePolicy{MaxEntries: 50, MaxBytes: 512 << 10}, false},
+		{models.CacheConfig{TTL: "7d", MaxSize: "2 mb"}, CachePolicy{TTL: 7 * 24 * time.Hour, MaxBytes: 2 << 20}, false},
+		{models.CacheConfig{TTL: "90m", MaxSize: "1000"}, CachePolicy{TTL: 90 * time.Minute, MaxBytes: 1000}, false},
+		{models.CacheConfig{TTL: "soon"}, CachePolicy{}, true},
+		{models.CacheConfig{MaxSize: "lots"}, CachePolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCachePolicy(tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCachePolicy(%+v) error = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseCachePolicy(%+v) = %+v, want %+v", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+	cache := newTestCache(t)
+	for _, q := range []string{"one", "two", "three"} {
+		cache.Set(q, "response")
+	}
+	cache.Get("one") // now more recent than two
+
+	cache.SetPolicy(CachePolicy{MaxEntries: 2})
+	if evicted, err := cache.Compact(); err != nil || evicted != 1 {
+		t.Fatalf("Compact() = %d, %v, want 1 eviction", evicted, err)
+	}
+	if _, found := cache.Get("two"); found {
+		t.Error("least recently used entry was not evicted")
+	}
+	if _, found := cache.Get("one"); !found {
+		t.Error("recently used entry was evicted")
+	}
+
+	// Saves keep the cache within its limits
+	cache.Set("four", "response")
+	if cache.Size() != 2 {
+		t.Errorf("Size() = %d after Set, want 2", cache.Size())
+	}
+}
+
+func TestHitsAreSavedLazily(t *testing.T) {
+	cache := newTestCache(t)
+	cache.Set("one", "response")
+	before, _ := os.ReadFile(cache.filePath)
+
+	cache.Get("one")
+	if after, _ := os.ReadFile(cache.filePath); string(after) != string(before) {
+		t.Error("Get() rewrote the cache file")
+	}
+
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	reloaded := NewCacheManager()
+	if entry := reloaded.Entries()[0]; entry.LastUsed.IsZero() {
+		t.Error("Flush() did not save the hit")
+	}
+}
+
+func TestEvictBySize(t *testing.T) {
+	cache := newTestCache(t)
+	large := strings.Repeat("x", 4000)
+	for _, q := range []string{"one", "two", "three"} {
+		cache.Set(q, large)
+	}
+
+	cache.SetPolicy(CachePolicy{MaxBytes: 9000})
+	if _, err := cache.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if cache.Size() != 2 {
+		t.Errorf("Size() = %d, want 2 entries within 9000 bytes", cache.Size())
+	}
+	info, err := os.Stat(cache.filePath)
+	if err != nil || info.Size() > 9000 {
+		t.Errorf("cache file is %d bytes, want at most 9000", info.Size())
+	}
+}
+
+func TestExpiryAndPinning(t *testing.T) {
+	cache := newTestCache(t)
+	cache.Set("old pinned", "a")
+	cache.Set("old", "b")
+	if err := cache.Pin(models.CacheScope{}, "old pinned", true); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if err := cache.Pin(models.CacheScope{}, "not cached", true); err == nil {
+		t.Error("Pin() of an uncached query should fail")
+	}
+
+	// Age both entries past a one hour TTL
+	cache.mu.Lock()
+	for key, entry := range cache.cache {
+		entry.Timestamp = time.Now().Add(-2 * time.Hour)
+		cache.cache[key] = entry
+	}
+	cache.mu.Unlock()
+
+	cache.SetPolicy(CachePolicy{TTL: 0})
+	if _, found := cache.Get("old"); !found {
+		t.Error("entry expired with ttl never")
+	}
+
+	cache.SetPolicy(CachePolicy{TTL: time.Hour, MaxEntries: 1})
+	cache.Compact()
+	if _, found := cache.Get("old"); found {
+		t.Error("expired entry was not removed")
+	}
+	if _, found := cache.Get("old pinned"); !found {
+		t.Error("pinned entry expired")
+	}
+
+	// Storing a new answer keeps the pin, and other processes see it
+	cache.Set("old pinned", "c")
+	if reloaded := NewCacheManager(); !reloaded.IsPinned(models.CacheScope{}, "old pinned") {
+		t.Error("pin was not saved")
+	}
+}
+
+func TestPinFromOtherProcess(t *testing.T) {
+	tui := newTestCache(t)
+	tui.Set("list files", "a")
+
+	cli := NewCacheManager()
+	if changed, err := cli.PinQuery("List files", true); err != nil || changed != 1 {
+		t.Fatalf("PinQuery() = %d, %v, want 1 change", changed, err)
+	}
+
+	// The long-running process saving its own changes keeps the pin
+	tui.Set("show disk usage", "b")
+	if reloaded := NewCacheManager(); !reloaded.IsPinned(models.CacheScope{}, "list files") {
+		t.Error("pin made by another process was lost")
+	}
+}
+
+func TestImportStrategies(t *testing.T) {
+	cache := newTestCache(t)
+	scope := models.CacheScope{Model: "m", OS: "linux", Prompt: "p", Schema: "s"}
+	cache.SetScoped(scope, "list files", "local")
+
+	now := time.Now()
+	imported := []models.CacheEntry{
+		{CacheScope: scope, Query: "List files", Response: "imported", Timestamp: now.Add(time.Minute)},
+		{CacheScope: scope, Query: "show disk usage", Response: "du", Timestamp: now},
+		{CacheScope: scope, Query: "ancient", Response: "old", Timestamp: now.Add(-48 * time.Hour)},
+	}
+
+	result, err := cache.Import(imported, MergeKeepExisting)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result != (ImportResult{Added: 2, Kept: 1, Dropped: 1}) {
+		t.Errorf("Import(existing) = %+v", result)
+	}
+	if got, _ := cache.GetScoped(scope, "list files"); got != "local" {
+		t.Errorf("keep existing replaced the entry with %q", got)
+	}
+
+	result, _ = cache.Import(imported[:1], MergeKeepNewest)
+	if result.Replaced != 1 {
+		t.Errorf("Import(newest) = %+v, want 1 replaced", result)
+	}
+	if got, _ := NewCacheManager().GetScoped(scope, "list files"); got != "imported" {
+		t.Errorf("keep newest kept %q", got)
+	}
+}
@@ -1,12 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"clify/internal/client"
 	"clify/internal/models"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -14,78 +16,160 @@ import (
 
 const (
 	DefaultConfigFile = "~/.clify/config.yaml"
-	DefaultModel      = "claude-3-sonnet-20240229"
+	DefaultModel      = "claude-sonnet-4-20250514"
+
+	// retiredModel was the default before the model was sent to the API;
+	// setup saved it in config files, where it now means the default
+	retiredModel = "claude-3-sonnet-20240229"
 )
 
-// getConfigPath returns the full path to the config file
-func getConfigPath() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	return filepath.Join(home, ".clify", "config.yaml"), nil
+// targetOverride replaces configured target fields, e.g. from command-line flags
+var targetOverride models.Target
+
+// SetTargetOverride makes every later LoadConfig use the non-empty fields of
+// target, over every other layer
+func SetTargetOverride(target models.Target) {
+	targetOverride = target
 }
 
+// offlineOverride is set by --offline
+var offlineOverride bool
+
+// SetOfflineOverride makes every later LoadConfig answer offline if offline
+// is true, over every other layer
+func SetOfflineOverride(offline bool) {
+	offlineOverride = offline
+}
+
+// LoadConfig resolves the effective configuration; see Resolve
 func LoadConfig() (*models.Config, error) {
-	config := &models.Config{
-		Model:     DefaultModel,
-		CacheFile: DefaultCacheFile,
-	}
+	r, err := Resolve()
+	return r.Config, err
+}
 
-	// Try to load from environment variable first
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		config.APIKey = apiKey
+// UpdateConfig sets keys, e.g. "encryption.enabled", in ~/.clify/config.yaml,
+// or in the XDG config file when only that one exists, keeping comments and the
+// settings not named. A nil value removes the key from every user config
+// file, so that it cannot come back from a lower layer.
+func UpdateConfig(values map[string]any) error {
+	files := UserConfigFiles()
+	if len(files) == 0 {
+		return fmt.Errorf("failed to get home directory")
 	}
-
-	// Try to load from config file
-	configPath, err := getConfigPath()
-	if err != nil {
-		return config, nil // Return default config if home dir not found
+	target := files[len(files)-1]
+	if _, err := os.Stat(target); os.IsNotExist(err) && len(files) > 1 {
+		if _, err := os.Stat(files[0]); err == nil {
+			target = files[0]
+		}
 	}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return config, nil // Config file doesn't exist, use defaults
+	for _, path := range files {
+		changes := map[string]any{}
+		for key, value := range values {
+			if value == nil || path == target {
+				changes[key] = value
+			}
+		}
+		if err := editConfigFile(path, changes, path == target); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return config, fmt.Errorf("failed to read config file: %w", err)
+// editConfigFile applies changes to the config file at path, creating it if
+// create is set
+func editConfigFile(path string, changes map[string]any, create bool) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err) && !create:
+		return nil
+	case err != nil && !os.IsNotExist(err):
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
-
-	if err := yaml.Unmarshal(data, config); err != nil {
-		return config, fmt.Errorf("failed to parse config file: %w", err)
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
-
-	// Environment variable takes precedence
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		config.APIKey = apiKey
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a mapping", path)
 	}
 
-	return config, nil
-}
-
-func SaveConfig(config *models.Config) error {
-	configPath, err := getConfigPath()
-	if err != nil {
-		return err
+	changed := false
+	for _, key := range sortedKeys(changes) {
+		ok, err := setNode(root, strings.Split(key, "."), changes[key])
+		if err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+		changed = changed || ok
+	}
+	if !changed {
+		return nil
 	}
 
-	configDir := filepath.Dir(configPath)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
-
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+	var out bytes.Buffer
+	if len(root.Content) > 0 {
+		enc := yaml.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
 	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	// The file may hold an API key or a command that prints one
+	if err := writeFileAtomic(path, out.Bytes(), 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 	return nil
 }
 
+// setNode sets, or with a nil value removes, the key at path under the
+// mapping node. It reports whether anything changed.
+func setNode(node *yaml.Node, path []string, value any) (bool, error) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != path[0] {
+			continue
+		}
+		child := node.Content[i+1]
+		switch {
+		case len(path) == 1 && value == nil:
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return true, nil
+		case len(path) == 1:
+			return true, child.Encode(value)
+		case child.Kind == yaml.MappingNode:
+			return setNode(child, path[1:], value)
+		case value == nil:
+			return false, nil
+		case child.Tag == "!!null":
+			// An empty section, e.g. "encryption:"
+			*child = yaml.Node{Kind: yaml.MappingNode}
+			return setNode(child, path[1:], value)
+		}
+		return false, fmt.Errorf("%s is not a mapping", path[0])
+	}
+	if value == nil {
+		return false, nil
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	if len(path) == 1 {
+		if err := child.Encode(value); err != nil {
+			return false, err
+		}
+	} else if _, err := setNode(child, path[1:], value); err != nil {
+		return false, err
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}, child)
+	return true, nil
+}
+
 func ValidateAPIKey(apiKey string) error {
 	if apiKey == "" {
 		return fmt.Errorf("API key is required")
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"clify/internal/credentials"
+	"clify/internal/crypt"
+	"clify/internal/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// CredentialBackend returns where the API key configured in cfg is kept
+func CredentialBackend(cfg *models.Config) (credentials.Backend, error) {
+	if cfg.APIKeyCommand != "" {
+		return credentials.Command{Command: cfg.APIKeyCommand}, nil
+	}
+	profile := credentialProfile(cfg)
+	switch cfg.CredentialStore {
+	case "", credentials.BackendKeyring:
+		return credentials.Keyring{Account: credentials.Account(profile)}, nil
+	case credentials.BackendFile:
+		return credentials.EncryptedFile{Path: filepath.Join(StateDir(), credentials.FileName(profile)), Key: credentialKey}, nil
+	}
+	return nil, fmt.Errorf("unknown credential_store %q (want keyring or file)", cfg.CredentialStore)
+}
+
+// credentialKey encrypts the credentials file: the encryption-at-rest key
+// if that is on, otherwise a key in a 0600 key file
+func credentialKey() (crypt.Key, error) {
+	key, err := EncryptionKey()
+	if err != nil || key != nil {
+		return key, err
+	}
+	key, _, err = crypt.CreateKey(StateDir(), crypt.SourceFile)
+	return key, err
+}
+
+// ResolveAPIKey fills in cfg.APIKey from api_key_command or the credential
+// store, unless it is set in the environment or, in plaintext, in the
+// config file
+func ResolveAPIKey(cfg *models.Config) error {
+	if cfg.APIKey != "" || (cfg.APIKeyCommand == "" && cfg.CredentialStore == "") {
+		return nil
+	}
+	backend, err := CredentialBackend(cfg)
+	if err != nil {
+		return err
+	}
+	apiKey, err := backend.Get()
+	if errors.Is(err, credentials.ErrNotFound) {
+		return nil // clify setup asks for one
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the API key from the %s: %w", backend.Name(), err)
+	}
+	cfg.APIKey = apiKey
+	return nil
+}
+
+// PlaintextAPIKey returns the API key written in a user config file itself
+func PlaintextAPIKey() (string, error) {
+	cfg, err := loadUserConfig()
+	return cfg.APIKey, err
+}
+
+// StoreAPIKey saves apiKey in the configured credential store, or in the
+// OS keyring if available and an encrypted file otherwise. Without a
+// profile, any plaintext key is removed from the config file; with one, the
+// key becomes the profile's own. It returns the backend used.
+func StoreAPIKey(profile, apiKey string) (credentials.Backend, error) {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	store, command := cfg.CredentialStore, cfg.APIKeyCommand
+	if profile != "" {
+		p, ok := cfg.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", profile)
+		}
+		if p.APIKeyEnv != "" {
+			return nil, fmt.Errorf("profile %s reads the key from $%s", profile, p.APIKeyEnv)
+		}
+		store, command = p.CredentialStore, p.APIKeyCommand
+	}
+	if command != "" {
+		return nil, errors.New("api_key_command is set; the key is read from it")
+	}
+	if store == "" {
+		store = credentials.BackendFile
+		if (credentials.Keyring{Account: credentials.Account(profile)}).Available() {
+			store = credentials.BackendKeyring
+		}
+	}
+
+	target := &models.Config{CredentialStore: store}
+	if profile != "" {
+		target.Profile = profile
+		target.Profiles = map[string]models.Profile{profile: {CredentialStore: store}}
+	}
+	backend, err := CredentialBackend(target)
+	if err != nil {
+		return nil, err
+	}
+	if err := backend.Set(apiKey); err != nil {
+		return nil, fmt.Errorf("failed to store the API key in the %s: %w", backend.Name(), err)
+	}
+
+	if profile == "" {
+		err = UpdateConfig(map[string]any{"api_key": nil, "credential_store": store})
+	} else {
+		err = UpdateConfig(map[string]any{"profiles." + profile + ".credential_store": store})
+	}
+	return backend, err
+}
+
+// PermissionWarnings lists config and credential files other users can
+// read, with how to fix them
+func PermissionWarnings() []string {
+	if runtime.GOOS == "windows" {
+		return nil // permissions come from ACLs, not mode bits
+	}
+
+	var warnings []string
+	paths := append(UserConfigFiles(), filepath.Join(StateDir(), credentials.File))
+	if cfg, err := loadUserConfig(); err == nil {
+		for _, name := range ProfileNames(cfg) {
+			paths = append(paths, filepath.Join(StateDir(), credentials.FileName(name)))
+		}
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.Mode().Perm()&0077 == 0 {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s is accessible by other users (mode %04o); run chmod 600 %s", path, info.Mode().Perm(), path))
+	}
+	return warnings
+}
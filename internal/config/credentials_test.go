@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"clify/internal/credentials"
+	"clify/internal/models"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, home, content string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(home, DefaultCacheDir, "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStoreAPIKeyMovesPlaintextKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	withKey(t, nil)
+	path := writeTestConfig(t, home, "api_key: sk-ant-plaintext-123\ncredential_store: file\nmodel: m\n", 0644)
+
+	if warnings := PermissionWarnings(); runtime.GOOS != "windows" && len(warnings) != 1 {
+		t.Errorf("PermissionWarnings() = %v, want one for the config file", warnings)
+	}
+
+	backend, err := StoreAPIKey("", "sk-ant-plaintext-123")
+	if err != nil {
+		t.Fatalf("StoreAPIKey() error = %v", err)
+	}
+	if backend.Name() != credentials.BackendFile {
+		t.Errorf("backend = %s, want the configured file store", backend.Name())
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "sk-ant") || !strings.Contains(string(data), "model: m") {
+		t.Errorf("config after StoreAPIKey = %q, want the key gone and other settings kept", data)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("config mode = %o, want 600", info.Mode().Perm())
+	}
+	stored, _ := os.ReadFile(filepath.Join(home, DefaultCacheDir, credentials.File))
+	if len(stored) == 0 || bytes.Contains(stored, []byte("sk-ant")) {
+		t.Errorf("credentials file = %q, want the key encrypted", stored)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveAPIKey(cfg); err != nil || cfg.APIKey != "sk-ant-plaintext-123" {
+		t.Errorf("ResolveAPIKey() = %q, %v, want the stored key", cfg.APIKey, err)
+	}
+	if warnings := PermissionWarnings(); len(warnings) != 0 {
+		t.Errorf("PermissionWarnings() after saving = %v, want none", warnings)
+	}
+}
+
+func TestResolveAPIKeyOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("api_key_command runs through sh")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := &models.Config{APIKeyCommand: "echo sk-from-command"}
+	if err := ResolveAPIKey(cfg); err != nil || cfg.APIKey != "sk-from-command" {
+		t.Errorf("ResolveAPIKey() = %q, %v, want the command's output", cfg.APIKey, err)
+	}
+
+	// A key from the environment is not replaced
+	cfg = &models.Config{APIKey: "sk-from-env", APIKeyCommand: "echo sk-from-command"}
+	if err := ResolveAPIKey(cfg); err != nil || cfg.APIKey != "sk-from-env" {
+		t.Errorf("ResolveAPIKey() = %q, %v, want the key already set", cfg.APIKey, err)
+	}
+
+	cfg = &models.Config{APIKeyCommand: "exit 3"}
+	if err := ResolveAPIKey(cfg); err == nil {
+		t.Error("ResolveAPIKey() with a failing command succeeded")
+	}
+}
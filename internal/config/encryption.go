@@ -0,0 +1,256 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"clify/internal/credentials"
+	"clify/internal/crypt"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// errNotEnabled means a file is encrypted but encryption is off in the config
+var errNotEnabled = errors.New("file is encrypted but encryption is not enabled; run clify encryption enable")
+
+// keyState is the key the cache, history and usage files are encrypted
+// with. It is loaded from the configured source, which may ask for a
+// passphrase, the first time a file needs it.
+var keyState struct {
+	mu     sync.Mutex
+	loaded bool
+	key    crypt.Key
+	err    error
+}
+
+// SetEncryptionKey makes later reads and writes use key instead of the
+// configured one. A nil key writes plaintext.
+func SetEncryptionKey(key crypt.Key) {
+	keyState.mu.Lock()
+	defer keyState.mu.Unlock()
+	keyState.loaded, keyState.key, keyState.err = true, key, nil
+}
+
+// EncryptionKey returns the configured key, or nil if encryption is off
+func EncryptionKey() (crypt.Key, error) {
+	keyState.mu.Lock()
+	defer keyState.mu.Unlock()
+	if keyState.loaded {
+		return keyState.key, keyState.err
+	}
+
+	keyState.loaded = true
+	cfg, err := LoadConfig()
+	if err != nil {
+		keyState.err = err
+		return nil, err
+	}
+	if !cfg.Encryption.Enabled {
+		return nil, nil
+	}
+	source, err := crypt.ParseSource(cfg.Encryption.KeySource)
+	if err == nil {
+		keyState.key, err = crypt.LoadKey(StateDir(), source)
+	}
+	if err != nil {
+		keyState.err = fmt.Errorf("failed to unlock the encryption key: %w", err)
+	}
+	return keyState.key, keyState.err
+}
+
+// StateDir is the directory of clify's cache, history and key files
+func StateDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, DefaultCacheDir)
+}
+
+// seal encrypts data for writing when encryption is on
+func seal(data []byte) ([]byte, error) {
+	key, err := EncryptionKey()
+	if err != nil || key == nil {
+		return data, err
+	}
+	return crypt.Seal(key, data)
+}
+
+// unseal decrypts data read from a file. Plaintext is returned as is, so
+// files written before encryption was turned on stay readable.
+func unseal(data []byte) ([]byte, error) {
+	if !crypt.IsSealed(data) {
+		return data, nil
+	}
+	key, err := EncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errNotEnabled
+	}
+	return crypt.Open(key, data)
+}
+
+// readSealed reads a file that may be encrypted
+func readSealed(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return unseal(data)
+}
+
+// Reseal rewrites the cache, history and usage files with key: plaintext
+// files are encrypted, or with a nil key, encrypted ones are decrypted.
+// Later reads and writes use key. It returns the files rewritten. The
+// credentials files are re-encrypted to match credentialKey but never
+// decrypted, and are not in the list.
+func Reseal(key crypt.Key) ([]string, error) {
+	// Files may be encrypted with the current key or, when encryption is
+	// turned back on, already with key
+	keys := []crypt.Key{key}
+	if current, err := EncryptionKey(); err == nil && current != nil {
+		keys = append(keys, current)
+	}
+	open := func(data []byte) ([]byte, error) {
+		if !crypt.IsSealed(data) {
+			return data, nil
+		}
+		for _, k := range keys {
+			if k == nil {
+				continue
+			}
+			if plain, err := crypt.Open(k, data); err == nil {
+				return plain, nil
+			}
+		}
+		return nil, crypt.ErrDecrypt
+	}
+	write := func(data []byte) ([]byte, error) {
+		if key == nil {
+			return data, nil
+		}
+		return crypt.Seal(key, data)
+	}
+
+	dir := StateDir()
+	// Without key, credentialKey falls back to the key file
+	credential := func(data []byte) ([]byte, error) {
+		plain, err := open(data)
+		if errors.Is(err, crypt.ErrDecrypt) {
+			if fileKey, loadErr := crypt.LoadKey(dir, crypt.SourceFile); loadErr == nil {
+				plain, err = crypt.Open(fileKey, data)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		sealKey := key
+		if sealKey == nil {
+			if sealKey, _, err = crypt.CreateKey(dir, crypt.SourceFile); err != nil {
+				return nil, err
+			}
+		}
+		sealed, err := crypt.Seal(sealKey, plain)
+		if err != nil {
+			return nil, err
+		}
+		return append(sealed, '\n'), nil
+	}
+
+	var rewritten []string
+	rewrite := func(path string, convert func([]byte) ([]byte, error)) error {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		out, err := convert(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := writeFileAtomic(path, out, 0600); err != nil {
+			return err
+		}
+		rewritten = append(rewritten, path)
+		return nil
+	}
+	whole := func(data []byte) ([]byte, error) {
+		plain, err := open(data)
+		if err != nil {
+			return nil, err
+		}
+		return write(plain)
+	}
+	lines := func(data []byte) ([]byte, error) {
+		var b bytes.Buffer
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			line, err := whole(scanner.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			b.Write(line)
+			b.WriteByte('\n')
+		}
+		return b.Bytes(), scanner.Err()
+	}
+
+	credentialNames := credentialFiles(dir)
+	groups := []struct {
+		lock    string // the file whose lock the group is rewritten under
+		files   []string
+		convert func([]byte) ([]byte, error)
+	}{
+		{DefaultCacheFile, []string{DefaultCacheFile, DefaultCacheFile + ".bak", DefaultCacheFile + ".corrupt"}, whole},
+		{DefaultHistoryFile, []string{DefaultHistoryFile}, lines},
+		{DefaultUsageFile, []string{DefaultUsageFile}, whole},
+		{credentials.File, credentialNames, credential},
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for _, group := range groups {
+		unlock, err := lockFile(filepath.Join(dir, group.lock))
+		if err != nil {
+			return rewritten, err
+		}
+		for _, name := range group.files {
+			if err := rewrite(filepath.Join(dir, name), group.convert); err != nil {
+				unlock()
+				return rewritten, err
+			}
+		}
+		unlock()
+	}
+
+	SetEncryptionKey(key)
+	return slices.DeleteFunc(rewritten, func(path string) bool {
+		return slices.Contains(credentialNames, filepath.Base(path))
+	}), nil
+}
+
+// credentialFiles lists the encrypted credentials files in dir: the
+// default key's and those of profiles
+func credentialFiles(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == credentials.File || (strings.HasPrefix(name, credentials.File+"-") && !strings.HasSuffix(name, ".tmp") && !strings.HasSuffix(name, ".lock")) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
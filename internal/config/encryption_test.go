@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"clify/internal/credentials"
+	"clify/internal/crypt"
+	"clify/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func withKey(t *testing.T, key crypt.Key) {
+	t.Helper()
+	SetEncryptionKey(key)
+	t.Cleanup(func() { SetEncryptionKey(nil) })
+}
+
+func TestEncryptedFilesAreTransparent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	key, _ := crypt.NewKey()
+	withKey(t, key)
+
+	cache := NewCacheManager()
+	if err := cache.Set("ssh into db01.internal", "response"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	history := NewHistory(DefaultHistorySize)
+	if _, err := history.Add("ssh into db01.internal"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := NewUsageTracker().Record(models.Usage{Query: "ssh into db01.internal", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	dir := filepath.Join(home, DefaultCacheDir)
+	for _, name := range []string{DefaultCacheFile, DefaultHistoryFile, DefaultUsageFile} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		if !crypt.IsSealed(data) || bytes.Contains(data, []byte("db01")) {
+			t.Errorf("%s is not encrypted: %q", name, data)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s mode = %o, want 600", name, perm)
+		}
+	}
+
+	if got, found := NewCacheManager().Get("ssh into db01.internal"); !found || got != "response" {
+		t.Errorf("Get() after reload = %q, %v, want the stored response", got, found)
+	}
+	if got := NewHistory(DefaultHistorySize).Queries(); len(got) != 1 {
+		t.Errorf("Queries() after reload = %v, want the query", got)
+	}
+
+	// With another key the files are left alone rather than replaced
+	other, _ := crypt.NewKey()
+	SetEncryptionKey(other)
+	locked := NewCacheManager()
+	if err := locked.Set("another query", "response"); err == nil {
+		t.Error("Set() with the wrong key overwrote the cache")
+	}
+}
+
+func TestResealMigratesPlaintext(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withKey(t, nil)
+
+	cache := NewCacheManager()
+	cache.Set("list files", "response")
+	NewHistory(DefaultHistorySize).Add("list files")
+
+	key, _ := crypt.NewKey()
+	files, err := Reseal(key)
+	if err != nil {
+		t.Fatalf("Reseal() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Reseal() rewrote %v, want the cache and history", files)
+	}
+	data, _ := os.ReadFile(filepath.Join(home, DefaultCacheDir, DefaultHistoryFile))
+	if bytes.Contains(data, []byte("list files")) {
+		t.Errorf("history still in plaintext: %q", data)
+	}
+	if got, found := NewCacheManager().Get("list files"); !found || got != "response" {
+		t.Errorf("Get() after migration = %q, %v", got, found)
+	}
+
+	// Decrypting brings the plaintext back
+	if _, err := Reseal(nil); err != nil {
+		t.Fatalf("Reseal(nil) error = %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(home, DefaultCacheDir, DefaultCacheFile))
+	if crypt.IsSealed(data) {
+		t.Error("cache still encrypted after Reseal(nil)")
+	}
+}
+
+func TestHistoryKeepsLinesWithAnotherKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, DefaultCacheDir, DefaultHistoryFile)
+
+	old, _ := crypt.NewKey()
+	withKey(t, old)
+	NewHistory(DefaultHistorySize).Add("list files")
+	oldLine, _ := os.ReadFile(path)
+	os.Remove(path)
+
+	key, _ := crypt.NewKey()
+	SetEncryptionKey(key)
+	NewHistory(DefaultHistorySize).Add("show disk usage")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(oldLine)
+	f.Close()
+
+	history := NewHistory(DefaultHistorySize)
+	if got := history.Queries(); len(got) != 1 || got[0] != "show disk usage" {
+		t.Errorf("Queries() = %v, want the readable entry", got)
+	}
+	if _, err := history.Add("list ports"); err == nil {
+		t.Error("Add() with an undecryptable line succeeded")
+	}
+	if data, _ := os.ReadFile(path); !bytes.Contains(data, oldLine) {
+		t.Error("the line sealed with another key was dropped")
+	}
+}
+
+func TestResealKeepsCredentialsReadable(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withKey(t, nil)
+
+	stores := map[string]credentials.EncryptedFile{}
+	for _, profile := range []string{"", "work"} {
+		path := filepath.Join(home, DefaultCacheDir, credentials.FileName(profile))
+		stores[path] = credentials.EncryptedFile{Path: path, Key: credentialKey}
+		if err := stores[path].Set("sk-ant-" + filepath.Base(path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(step string) {
+		t.Helper()
+		for path, store := range stores {
+			if got, err := store.Get(); err != nil || got != "sk-ant-"+filepath.Base(path) {
+				t.Errorf("%s: Get(%s) = %q, %v, want the stored key", step, filepath.Base(path), got, err)
+			}
+			if data, _ := os.ReadFile(path); !crypt.IsSealed(data) {
+				t.Errorf("%s: %s is not encrypted", step, filepath.Base(path))
+			}
+		}
+	}
+
+	key, _ := crypt.NewKey()
+	files, err := Reseal(key)
+	if err != nil {
+		t.Fatalf("Reseal() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("Reseal() listed %v, want the credentials files left out", files)
+	}
+	check("enabled")
+
+	if _, err := Reseal(nil); err != nil {
+		t.Fatalf("Reseal(nil) error = %v", err)
+	}
+	check("disabled")
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"clify/internal/fuzzy"
+	"clify/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultFavoritesFile = "favorites.yaml"
+	// FavoriteMatchScore is the score (0-1) at which a favorite answers a query
+	FavoriteMatchScore = 0.8
+)
+
+// favoritesFile is the on-disk format of favorites.yaml
+type favoritesFile struct {
+	Favorites []models.Favorite `yaml:"favorites"`
+}
+
+// Favorites is the personal library of saved commands in
+// ~/.clify/favorites.yaml. Every change re-reads the file under the lock,
+// so edits by hand or by other processes are kept.
+type Favorites struct {
+	mu        sync.Mutex
+	filePath  string
+	favorites []models.Favorite // in the order they were added
+}
+
+// NewFavorites loads ~/.clify/favorites.yaml
+func NewFavorites() *Favorites {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+
+	f := &Favorites{
+		filePath: filepath.Join(home, DefaultCacheDir, DefaultFavoritesFile),
+	}
+	if err := os.MkdirAll(filepath.Dir(f.filePath), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to create favorites directory: %v\n", err)
+	}
+	if err := f.read(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load favorites: %v\n", err)
+	}
+	return f
+}
+
+// List returns every favorite, oldest first
+func (f *Favorites) List() []models.Favorite {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.favorites)
+}
+
+// Get returns the favorite for command
+func (f *Favorites) Get(command string) (models.Favorite, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if i := f.index(command); i >= 0 {
+		return f.favorites[i], true
+	}
+	return models.Favorite{}, false
+}
+
+// Add saves favorite, replacing an earlier one for the same command
+func (f *Favorites) Add(favorite models.Favorite) error {
+	return f.update(func() {
+		if i := f.index(favorite.Command); i >= 0 {
+			f.favorites[i] = favorite
+			return
+		}
+		f.favorites = append(f.favorites, favorite)
+	})
+}
+
+// Remove deletes the favorite for command and reports whether there was one
+func (f *Favorites) Remove(command string) (bool, error) {
+	removed := false
+	err := f.update(func() {
+		if i := f.index(command); i >= 0 {
+			f.favorites = slices.Delete(f.favorites, i, i+1)
+			removed = true
+		}
+	})
+	return removed, err
+}
+
+// Import merges favorites, matching them to existing ones by command
+func (f *Favorites) Import(favorites []models.Favorite, strategy MergeStrategy) (ImportResult, error) {
+	var result ImportResult
+	err := f.update(func() {
+		for _, favorite := range favorites {
+			i := f.index(favorite.Command)
+			switch {
+			case i < 0:
+				result.Added++
+				f.favorites = append(f.favorites, favorite)
+			case strategy.replaces(favorite.Added, f.favorites[i].Added):
+				result.Replaced++
+				f.favorites[i] = favorite
+			default:
+				result.Kept++
+			}
+		}
+	})
+	return result, err
+}
+
+// Tags returns the tags in use, sorted
+func (f *Favorites) Tags() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var tags []string
+	for _, favorite := range f.favorites {
+		for _, tag := range favorite.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Match returns the favorites that answer query, best first: those whose
+// query is similar to it or whose description, tags and command cover it
+func (f *Favorites) Match(query string, minScore float64) []models.Favorite {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	type scored struct {
+		favorite models.Favorite
+		score    float64
+	}
+	var matches []scored
+	for _, favorite := range f.favorites {
+		text := strings.Join(append([]string{favorite.Description, favorite.Command}, favorite.Tags...), " ")
+		score := max(fuzzy.Similarity(query, favorite.Query), fuzzy.Coverage(query, text))
+		if score >= minScore {
+			matches = append(matches, scored{favorite, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	result := make([]models.Favorite, len(matches))
+	for i, m := range matches {
+		result[i] = m.favorite
+	}
+	return result
+}
+
+// HasTag reports whether favorite is tagged tag, ignoring case
+func HasTag(favorite models.Favorite, tag string) bool {
+	return slices.ContainsFunc(favorite.Tags, func(t string) bool {
+		return strings.EqualFold(t, tag)
+	})
+}
+
+// ParseTags splits a comma- or space-separated tag list
+func ParseTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if tag = strings.ToLower(tag); !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (f *Favorites) index(command string) int {
+	command = strings.TrimSpace(command)
+	return slices.IndexFunc(f.favorites, func(favorite models.Favorite) bool {
+		return strings.TrimSpace(favorite.Command) == command
+	})
+}
+
+// update applies change to the favorites as currently on disk and saves them
+func (f *Favorites) update(change func()) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	unlock, err := lockFile(f.filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := f.read(); err != nil {
+		return err
+	}
+	change()
+
+	data, err := yaml.Marshal(favoritesFile{Favorites: f.favorites})
+	if err != nil {
+		return fmt.Errorf("failed to marshal favorites: %w", err)
+	}
+	if err := writeFileAtomic(f.filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write favorites: %w", err)
+	}
+	return nil
+}
+
+func (f *Favorites) read() error {
+	data, err := os.ReadFile(f.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			f.favorites = nil
+			return nil
+		}
+		return fmt.Errorf("failed to read favorites: %w", err)
+	}
+
+	var file favoritesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", f.filePath, err)
+	}
+	f.favorites = file.Favorites
+	return nil
+}
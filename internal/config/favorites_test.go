@@ -0,0 +1,80 @@
+package config
+
+import (
+	"clify/internal/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFavoritesAddRemove(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	favorites := NewFavorites()
+
+	disk := models.Favorite{Command: "du -sh *", Query: "show disk usage", Tags: []string{"disk"}, SafetyLevel: "safe"}
+	ports := models.Favorite{Command: "lsof -i -P", Query: "list open ports", Tags: []string{"net"}, SafetyLevel: "safe"}
+	for _, favorite := range []models.Favorite{disk, ports} {
+		if err := favorites.Add(favorite); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	// Saving the same command again updates it in place
+	disk.Tags = []string{"disk", "daily"}
+	favorites.Add(disk)
+
+	reloaded := NewFavorites()
+	if got := reloaded.List(); len(got) != 2 || !reflect.DeepEqual(got[0].Tags, disk.Tags) {
+		t.Errorf("List() after reload = %+v", got)
+	}
+	if got := reloaded.Tags(); !reflect.DeepEqual(got, []string{"daily", "disk", "net"}) {
+		t.Errorf("Tags() = %v", got)
+	}
+
+	if removed, err := reloaded.Remove("du -sh *"); !removed || err != nil {
+		t.Fatalf("Remove() = %v, %v", removed, err)
+	}
+	if _, found := NewFavorites().Get("du -sh *"); found {
+		t.Error("removed favorite is still saved")
+	}
+
+	info, err := os.Stat(filepath.Join(home, DefaultCacheDir, DefaultFavoritesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("favorites.yaml mode = %o, want 600", perm)
+	}
+}
+
+func TestFavoritesMatch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	favorites := NewFavorites()
+	favorites.Add(models.Favorite{Command: "du -sh *", Query: "show disk usage", Description: "Size of each entry"})
+	favorites.Add(models.Favorite{Command: "lsof -i -P", Query: "list open ports", Tags: []string{"network"}})
+
+	tests := map[string]string{
+		"show the disk usage": "du -sh *",
+		"open ports":          "lsof -i -P",
+		"network":             "lsof -i -P",
+		"kill a process":      "",
+	}
+	for query, want := range tests {
+		matches := favorites.Match(query, FavoriteMatchScore)
+		got := ""
+		if len(matches) > 0 {
+			got = matches[0].Command
+		}
+		if got != want {
+			t.Errorf("Match(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	if got := ParseTags("Disk, daily  disk"); !reflect.DeepEqual(got, []string{"disk", "daily"}) {
+		t.Errorf("ParseTags() = %v", got)
+	}
+}
@@ -0,0 +1,417 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"clify/internal/crypt"
+	"clify/internal/fuzzy"
+	"clify/internal/models"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHistoryFile = "history.jsonl"
+	DefaultHistorySize = 1000
+)
+
+// History is the chronological list of queries, kept apart from the cache
+// so that it survives cache expiry. The file is append-only JSON lines; a
+// later line with the same ID updates an entry, e.g. with the chosen
+// command. Deleting and trimming rewrite the file.
+type History struct {
+	mu       sync.Mutex
+	filePath string
+	maxSize  int
+	entries  []models.HistoryEntry // oldest first, one per normalized query
+	index    map[string]int        // normalized query to position in entries
+	lines    int                   // lines in the file, including superseded ones
+	stat     os.FileInfo           // the file as last read or written, to notice other writers
+}
+
+// NewHistory loads ~/.clify/history.jsonl. maxSize bounds the number of
+// entries kept; 0 disables recording.
+func NewHistory(maxSize int) *History {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+
+	h := &History{
+		filePath: filepath.Join(home, DefaultCacheDir, DefaultHistoryFile),
+		maxSize:  maxSize,
+	}
+	if err := os.MkdirAll(filepath.Dir(h.filePath), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to create history directory: %v\n", err)
+	}
+	if err := h.load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load history: %v\n", err)
+	}
+	return h
+}
+
+// Add records query asked in the working directory and returns the new
+// entry's ID. An earlier entry for the same query is replaced.
+func (h *History) Add(query string) (string, error) {
+	if h.maxSize <= 0 {
+		return "", nil
+	}
+
+	cwd, _ := os.Getwd()
+	entry := models.HistoryEntry{
+		ID:        newHistoryID(),
+		Timestamp: time.Now(),
+		Query:     query,
+		Cwd:       cwd,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return entry.ID, h.append(entry)
+}
+
+// SetCommand records the command chosen for the entry with id
+func (h *History) SetCommand(id, command string) error {
+	if id == "" {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, entry := range h.entries {
+		if entry.ID == id {
+			entry.Command = command
+			return h.append(entry)
+		}
+	}
+	return fmt.Errorf("no history entry %s", id)
+}
+
+// Entries returns all entries, oldest first
+func (h *History) Entries() []models.HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]models.HistoryEntry(nil), h.entries...)
+}
+
+// Queries returns the distinct queries, oldest first, for Up/Down recall
+func (h *History) Queries() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	queries := make([]string, len(h.entries))
+	for i, entry := range h.entries {
+		queries[i] = entry.Query
+	}
+	return queries
+}
+
+// Search returns entries whose query or command matches text, best match
+// first and newest first among equal matches
+func (h *History) Search(text string) []models.HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	type scored struct {
+		entry models.HistoryEntry
+		score float64
+	}
+	var matches []scored
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		entry := h.entries[i]
+		score := fuzzy.Coverage(text, entry.Query+" "+entry.Command)
+		if score >= 0.5 {
+			matches = append(matches, scored{entry, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	result := make([]models.HistoryEntry, len(matches))
+	for i, m := range matches {
+		result[i] = m.entry
+	}
+	return result
+}
+
+// Import merges entries into the history, matching them to existing
+// entries by query, and keeps the result in chronological order
+func (h *History) Import(entries []models.HistoryEntry, strategy MergeStrategy) (ImportResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	unlock, err := lockFile(h.filePath)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer unlock()
+
+	if err := h.refresh(); err != nil {
+		return ImportResult{}, err
+	}
+
+	ids := make(map[string]bool, len(h.entries))
+	for _, entry := range h.entries {
+		ids[entry.ID] = true
+	}
+	var result ImportResult
+	for _, entry := range entries {
+		i, found := h.index[NormalizeQuery(entry.Query)]
+		switch {
+		case !found:
+			result.Added++
+		case strategy.replaces(entry.Timestamp, h.entries[i].Timestamp):
+			result.Replaced++
+		default:
+			result.Kept++
+			continue
+		}
+		if entry.ID == "" || ids[entry.ID] && !(found && h.entries[i].ID == entry.ID) {
+			entry.ID = newHistoryID()
+		}
+		ids[entry.ID] = true
+		h.insert(entry)
+	}
+
+	h.entries = slices.DeleteFunc(h.entries, func(entry models.HistoryEntry) bool { return entry.ID == "" })
+	sort.SliceStable(h.entries, func(i, j int) bool {
+		return h.entries[i].Timestamp.Before(h.entries[j].Timestamp)
+	})
+	before := len(h.entries)
+	err = h.rewrite()
+	result.Dropped = before - len(h.entries)
+	return result, err
+}
+
+// Delete removes the entries with the given IDs and returns how many it removed
+func (h *History) Delete(ids ...string) (int, error) {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	unlock, err := lockFile(h.filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	// Start from the file so that entries other processes added survive
+	if err := h.refresh(); err != nil {
+		return 0, err
+	}
+	kept := h.entries[:0]
+	for _, entry := range h.entries {
+		if !remove[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	removed := len(h.entries) - len(kept)
+	h.entries = kept
+	return removed, h.rewrite()
+}
+
+// Clear removes every entry
+func (h *History) Clear() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	unlock, err := lockFile(h.filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	h.entries = nil
+	return h.rewrite()
+}
+
+func (h *History) load() error {
+	unlock, err := lockFile(h.filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return h.read()
+}
+
+// read replaces the in-memory entries with the file's. Lines that do not
+// parse, e.g. one cut short by a crash, are skipped. Lines that do not
+// decrypt are an error, so that a rewrite never drops them; the entries
+// that did decrypt are still loaded for reading. The caller holds the file
+// lock.
+func (h *History) read() error {
+	h.stat = nil
+	info, err := os.Stat(h.filePath)
+	var data []byte
+	if err == nil {
+		data, err = os.ReadFile(h.filePath)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.entries, h.lines = nil, 0
+			h.compact()
+			return nil
+		}
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	h.entries, h.index, h.lines = nil, nil, 0
+	undecryptable := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Lines are encrypted one by one so that appending stays cheap
+		line, err := unseal(scanner.Bytes())
+		if errors.Is(err, crypt.ErrDecrypt) {
+			undecryptable++
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+
+		var entry models.HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil || entry.ID == "" {
+			continue
+		}
+		h.lines++
+		h.insert(entry)
+	}
+	h.compact()
+	if undecryptable > 0 {
+		return fmt.Errorf("failed to read history: %d lines: %w", undecryptable, crypt.ErrDecrypt)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	h.stat = info
+	return nil
+}
+
+// refresh reads the file again if another process changed it since this
+// one last read or wrote it. The caller holds the file lock.
+func (h *History) refresh() error {
+	info, err := os.Stat(h.filePath)
+	if err == nil && h.stat != nil && os.SameFile(info, h.stat) &&
+		info.Size() == h.stat.Size() && info.ModTime().Equal(h.stat.ModTime()) {
+		return nil
+	}
+	return h.read()
+}
+
+// insert adds or updates entry in memory. An older entry for the same
+// query is left as a gap, with no ID, for compact to drop.
+func (h *History) insert(entry models.HistoryEntry) {
+	normalized := NormalizeQuery(entry.Query)
+	if i, ok := h.index[normalized]; ok {
+		if h.entries[i].ID == entry.ID {
+			h.entries[i] = entry
+			return
+		}
+		h.entries[i].ID = ""
+	}
+	if h.index == nil {
+		h.index = make(map[string]int)
+	}
+	h.index[normalized] = len(h.entries)
+	h.entries = append(h.entries, entry)
+}
+
+// compact drops the gaps insert left and the oldest entries beyond
+// maxSize, and indexes the rest
+func (h *History) compact() {
+	h.entries = slices.DeleteFunc(h.entries, func(entry models.HistoryEntry) bool { return entry.ID == "" })
+	if h.maxSize > 0 && len(h.entries) > h.maxSize {
+		h.entries = h.entries[len(h.entries)-h.maxSize:]
+	}
+	h.index = make(map[string]int, len(h.entries))
+	for i, entry := range h.entries {
+		h.index[NormalizeQuery(entry.Query)] = i
+	}
+}
+
+// append writes entry as a new line. Once superseded lines and entries
+// beyond maxSize make up half the file, it is rewritten instead.
+func (h *History) append(entry models.HistoryEntry) error {
+	unlock, err := lockFile(h.filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Pick up lines other processes appended
+	if err := h.refresh(); err != nil {
+		return err
+	}
+	h.insert(entry)
+	h.compact()
+
+	if h.lines+1 > 2*max(h.maxSize, len(h.entries)) {
+		return h.rewrite()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	if data, err = seal(data); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	h.lines++
+	h.stat, _ = f.Stat()
+	return nil
+}
+
+// rewrite replaces the file with the newest maxSize entries. The caller
+// holds the file lock.
+func (h *History) rewrite() error {
+	h.compact()
+
+	var b bytes.Buffer
+	for _, entry := range h.entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		if data, err = seal(data); err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+
+	if err := writeFileAtomic(h.filePath, b.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	h.lines = len(h.entries)
+	h.stat, _ = os.Stat(h.filePath)
+	return nil
+}
+
+func newHistoryID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
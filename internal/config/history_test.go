@@ -0,0 +1,166 @@
+package config
+
+import (
+	"clify/internal/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryOrderAndDedup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	history := NewHistory(DefaultHistorySize)
+
+	for _, query := range []string{"list files", "show disk usage", "List files!", "find big files"} {
+		if _, err := history.Add(query); err != nil {
+			t.Fatalf("Add(%q) error = %v", query, err)
+		}
+	}
+
+	// A repeated query moves to the end instead of appearing twice
+	want := []string{"show disk usage", "List files!", "find big files"}
+	if got := history.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries() = %v, want %v", got, want)
+	}
+	if got := NewHistory(DefaultHistorySize).Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries() after reload = %v, want %v", got, want)
+	}
+
+	cwd, _ := os.Getwd()
+	if entry := history.Entries()[0]; entry.Cwd != cwd || entry.Timestamp.IsZero() {
+		t.Errorf("entry = %+v, want cwd %s and a timestamp", entry, cwd)
+	}
+}
+
+func TestHistorySetCommandAndSearch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	history := NewHistory(DefaultHistorySize)
+
+	id, _ := history.Add("show disk usage")
+	history.Add("list files")
+	if err := history.SetCommand(id, "du -sh *"); err != nil {
+		t.Fatalf("SetCommand() error = %v", err)
+	}
+
+	reloaded := NewHistory(DefaultHistorySize)
+	if entry := reloaded.Entries()[0]; entry.Command != "du -sh *" {
+		t.Errorf("Command = %q after reload, want du -sh *", entry.Command)
+	}
+	if got := reloaded.Queries(); !reflect.DeepEqual(got, []string{"show disk usage", "list files"}) {
+		t.Errorf("Queries() = %v; recording a command must not reorder", got)
+	}
+
+	matches := reloaded.Search("disk")
+	if len(matches) != 1 || matches[0].ID != id {
+		t.Errorf("Search(disk) = %+v, want the disk usage entry", matches)
+	}
+}
+
+func TestHistoryMaxSizeAndDelete(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	history := NewHistory(3)
+
+	var ids []string
+	for _, query := range []string{"one", "two", "three", "four", "five", "six", "seven"} {
+		id, err := history.Add(query)
+		if err != nil {
+			t.Fatalf("Add(%q) error = %v", query, err)
+		}
+		ids = append(ids, id)
+	}
+	if got := NewHistory(3).Queries(); !reflect.DeepEqual(got, []string{"five", "six", "seven"}) {
+		t.Errorf("Queries() = %v, want the newest 3", got)
+	}
+
+	removed, err := history.Delete(ids[5])
+	if err != nil || removed != 1 {
+		t.Fatalf("Delete() = %d, %v, want 1 removed", removed, err)
+	}
+	if got := NewHistory(3).Queries(); !reflect.DeepEqual(got, []string{"five", "seven"}) {
+		t.Errorf("Queries() after delete = %v", got)
+	}
+
+	// Disabled history records nothing
+	if err := history.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if id, err := NewHistory(0).Add("eight"); id != "" || err != nil {
+		t.Errorf("Add() with history disabled = %q, %v", id, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(home, DefaultCacheDir, DefaultHistoryFile))
+	if len(data) != 0 {
+		t.Errorf("history file = %q, want empty", data)
+	}
+}
+
+func TestHistorySeesOtherProcesses(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	first := NewHistory(DefaultHistorySize)
+	second := NewHistory(DefaultHistorySize)
+
+	first.Add("list files")
+	id, _ := second.Add("show disk usage")
+	first.Add("find big files")
+	if got := first.Queries(); !reflect.DeepEqual(got, []string{"list files", "show disk usage", "find big files"}) {
+		t.Errorf("Queries() = %v, want the other history's entry too", got)
+	}
+
+	// A rewrite by the other process is picked up as well
+	if removed, err := first.Delete(id); err != nil || removed != 1 {
+		t.Fatalf("Delete() = %d, %v, want 1 removed", removed, err)
+	}
+	second.Add("list files")
+	want := []string{"find big files", "list files"}
+	if got := second.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries() after the other's delete = %v, want %v", got, want)
+	}
+	if got := NewHistory(DefaultHistorySize).Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Queries() after reload = %v, want %v", got, want)
+	}
+}
+
+func TestHistorySkipsCorruptLines(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	NewHistory(DefaultHistorySize).Add("list files")
+
+	path := filepath.Join(home, DefaultCacheDir, DefaultHistoryFile)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id": "cut sho`)
+	f.Close()
+
+	history := NewHistory(DefaultHistorySize)
+	if got := history.Queries(); !reflect.DeepEqual(got, []string{"list files"}) {
+		t.Errorf("Queries() = %v, want the intact entry", got)
+	}
+}
+
+func TestHistoryImport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	history := NewHistory(DefaultHistorySize)
+	history.Add("list files")
+	local := history.Entries()[0]
+
+	imported := []models.HistoryEntry{
+		{Query: "show disk usage", Timestamp: local.Timestamp.Add(-time.Hour), Command: "du -sh *"},
+		{ID: local.ID, Query: "List files", Timestamp: local.Timestamp.Add(time.Hour), Command: "ls -la"},
+	}
+	result, err := history.Import(imported, MergeKeepNewest)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result != (ImportResult{Added: 1, Replaced: 1}) {
+		t.Errorf("Import() = %+v", result)
+	}
+
+	// Imported entries take their place in time
+	if got := NewHistory(DefaultHistorySize).Queries(); !reflect.DeepEqual(got, []string{"show disk usage", "List files"}) {
+		t.Errorf("Queries() = %v", got)
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// MergeStrategy decides which entry wins when an imported entry collides
+// with an existing one
+type MergeStrategy string
+
+const (
+	MergeKeepNewest   MergeStrategy = "newest"
+	MergeKeepExisting MergeStrategy = "existing"
+)
+
+// ParseMergeStrategy accepts "newest" (the default when empty) or "existing"
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch MergeStrategy(s) {
+	case "", MergeKeepNewest:
+		return MergeKeepNewest, nil
+	case MergeKeepExisting:
+		return MergeKeepExisting, nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy %q; use newest or existing", s)
+	}
+}
+
+// ImportResult counts what an import did with each entry
+type ImportResult struct {
+	Added    int
+	Replaced int
+	Kept     int // the existing entry won
+	Dropped  int // over the size or age limits of the store
+}
+
+func (r ImportResult) String() string {
+	s := fmt.Sprintf("%d added, %d replaced, %d kept", r.Added, r.Replaced, r.Kept)
+	if r.Dropped > 0 {
+		s += fmt.Sprintf(", %d dropped by size or age limits", r.Dropped)
+	}
+	return s
+}
+
+// replaces reports whether an entry imported with timestamp imported
+// replaces an existing one with timestamp existing
+func (s MergeStrategy) replaces(imported, existing time.Time) bool {
+	return s == MergeKeepNewest && imported.After(existing)
+}
@@ -0,0 +1,516 @@
+package config
+
+import (
+	"clify/internal/client"
+	"clify/internal/envinfo"
+	"clify/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the project-local config file, found by walking up from
+// the working directory
+const ProjectFile = ".clify.yaml"
+
+// OriginDefault marks built-in default values
+const OriginDefault = "default"
+
+// Setting is an effective configuration value and where it came from
+type Setting struct {
+	Key    string // dotted path, e.g. "cache.ttl"
+	Value  any
+	Origin string // "default", a file, "profile NAME", "env VAR" or "flag --NAME"
+}
+
+// Resolution is the effective configuration and the origin of each value
+type Resolution struct {
+	Config   *models.Config
+	Settings []Setting // sorted by key
+	Ignored  []string  // project settings that were not applied, and why
+}
+
+// settings are configuration values flattened to dotted keys, e.g.
+// "budget.monthly". Lists are single values.
+type settings map[string]any
+
+// layers merges settings, remembering which layer set each key last
+type layers struct {
+	values  settings
+	origins map[string]string
+}
+
+func (l *layers) apply(values settings, origin string) {
+	for key, value := range values {
+		l.values[key] = value
+		l.origins[key] = origin
+	}
+}
+
+// Resolve merges, lowest precedence first: the built-in defaults, the XDG
+// config file, ~/.clify/config.yaml, the nearest .clify.yaml, then
+// $ANTHROPIC_API_KEY, the active profile, CLIFY_* variables and flags.
+// A project file cannot set secrets, and its policy settings apply last,
+// only where they are stricter than the value of every other layer.
+func Resolve() (*Resolution, error) {
+	r := &Resolution{}
+	l, err := loadUserLayers()
+	if err != nil {
+		r.Config, _ = decodeSettings(l.values)
+		return r, err
+	}
+
+	cwd, _ := os.Getwd()
+	projectPath := findProjectFile(cwd)
+	policy := settings{}
+	if projectPath != "" {
+		values, err := readSettings(projectPath)
+		if err != nil {
+			r.Config, _ = decodeSettings(l.values)
+			return r, err
+		}
+		for _, key := range sortedKeys(values) {
+			if slices.Contains(policyKeys, key) {
+				policy[key] = values[key]
+				delete(values, key)
+			} else if reason := projectRestriction(key, values[key], l.values[key]); reason != "" {
+				r.Ignored = append(r.Ignored, fmt.Sprintf("%s: ignoring %s (%s)", projectPath, key, reason))
+				delete(values, key)
+			}
+		}
+		l.apply(values, projectPath)
+	}
+
+	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+		l.apply(settings{"api_key": apiKey}, "env ANTHROPIC_API_KEY")
+	}
+
+	// The profile is chosen from the merged files
+	var profile string
+	cfg, err := decodeSettings(l.values)
+	if err == nil {
+		profile, err = selectProfile(cfg, cwd)
+	}
+	if profile != "" {
+		l.apply(profileSettings(l.values, profile, cfg.Profiles[profile]), "profile "+profile)
+		l.apply(settings{"profile": profile}, profileOrigin(profile))
+	}
+	if err != nil {
+		r.Config = cfg
+		return r, err
+	}
+
+	env, err := envSettings()
+	if err != nil {
+		r.Config = cfg
+		return r, err
+	}
+	for _, key := range sortedKeys(env) {
+		l.apply(settings{key: env[key]}, "env "+envName(key))
+	}
+
+	for key, flag := range map[string]struct{ name, value string }{
+		"target.os":     {"--os", targetOverride.OS},
+		"target.shell":  {"--shell", targetOverride.Shell},
+		"target.distro": {"--distro", targetOverride.Distro},
+	} {
+		if flag.value != "" {
+			l.apply(settings{key: flag.value}, "flag "+flag.name)
+		}
+	}
+	if offlineOverride {
+		l.apply(settings{"offline": true}, "flag --offline")
+	}
+
+	// Checked against the effective values, so that neither a looser
+	// profile replaces a project's policy nor the project loosens one
+	for _, key := range sortedKeys(policy) {
+		if reason := projectRestriction(key, policy[key], l.values[key]); reason != "" {
+			r.Ignored = append(r.Ignored, fmt.Sprintf("%s: ignoring %s (%s)", projectPath, key, reason))
+			continue
+		}
+		l.apply(settings{key: policy[key]}, projectPath)
+	}
+
+	if r.Config, err = decodeSettings(l.values); err != nil {
+		return r, err
+	}
+	r.Config.Profile = profile
+	r.Config.Prompts = ExpandHome(r.Config.Prompts)
+	for _, key := range sortedKeys(l.values) {
+		r.Settings = append(r.Settings, Setting{Key: key, Value: l.values[key], Origin: l.origins[key]})
+	}
+	return r, nil
+}
+
+// ProjectWarnings lists the settings of the project file that were ignored
+func ProjectWarnings() []string {
+	r, _ := Resolve()
+	return r.Ignored
+}
+
+// defaultConfig returns the built-in defaults
+func defaultConfig() *models.Config {
+	return &models.Config{
+		Model:               DefaultModel,
+		CacheFile:           DefaultCacheFile,
+		RepairAttempts:      client.DefaultRepairAttempts,
+		SimilarityThreshold: DefaultSimilarityThreshold,
+		Cache: models.CacheConfig{
+			TTL:        "24h",
+			MaxEntries: DefaultCachePolicy.MaxEntries,
+			MaxSize:    "10MB",
+		},
+		HistorySize: DefaultHistorySize,
+	}
+}
+
+// UserConfigFiles returns the XDG config file and ~/.clify/config.yaml,
+// lowest precedence first. Either may not exist.
+func UserConfigFiles() []string {
+	var files []string
+	home, homeErr := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && homeErr == nil {
+		xdg = filepath.Join(home, ".config")
+	}
+	if xdg != "" {
+		files = append(files, filepath.Join(xdg, "clify", "config.yaml"))
+	}
+	if homeErr == nil {
+		files = append(files, filepath.Join(home, DefaultCacheDir, "config.yaml"))
+	}
+	return files
+}
+
+// loadUserLayers merges the defaults and the user config files
+func loadUserLayers() (*layers, error) {
+	l := &layers{values: settings{}, origins: map[string]string{}}
+	defaults, err := toSettings(defaultConfig())
+	if err != nil {
+		return l, err
+	}
+	l.apply(defaults, OriginDefault)
+
+	for _, path := range UserConfigFiles() {
+		values, err := readSettings(path)
+		if err != nil {
+			return l, err
+		}
+		l.apply(values, path)
+	}
+	return l, nil
+}
+
+// loadUserConfig returns the configuration of the defaults and user config
+// files alone, as clify setup and the encryption commands edit them
+func loadUserConfig() (*models.Config, error) {
+	l, err := loadUserLayers()
+	if err != nil {
+		return defaultConfig(), err
+	}
+	return decodeSettings(l.values)
+}
+
+// findProjectFile returns the nearest .clify.yaml in dir or its parents
+func findProjectFile(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readSettings reads a config file; a missing one has no settings
+func readSettings(path string) (settings, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	// Check the types now, so that the error names the file
+	var cfg models.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := settings{}
+	flatten("", tree, values)
+	if values["model"] == retiredModel {
+		delete(values, "model")
+	}
+	return values, nil
+}
+
+// profileSettings returns the settings profile name overrides: the keys its
+// entry in the merged values sets, so that e.g. a profile's budget.monthly
+// alone keeps the inherited budget.action. A profile's key reference
+// replaces the one inherited.
+func profileSettings(merged settings, name string, p models.Profile) settings {
+	keyEnv, command, store := p.APIKeyEnv, p.APIKeyCommand, p.CredentialStore
+
+	values := settings{}
+	for key, value := range merged {
+		key, ok := strings.CutPrefix(key, "profiles."+name+".")
+		if !ok || value == nil {
+			continue
+		}
+		switch key {
+		case "dirs", "api_key_env", "api_key_command", "credential_store":
+			continue
+		}
+		values[key] = value
+	}
+	switch {
+	case keyEnv != "":
+		values["api_key"], values["api_key_command"], values["credential_store"] = os.Getenv(keyEnv), "", ""
+	case command != "":
+		values["api_key"], values["api_key_command"], values["credential_store"] = "", command, ""
+	case store != "":
+		values["api_key"], values["api_key_command"], values["credential_store"] = "", "", store
+	}
+	return values
+}
+
+// envSettings reads CLIFY_<KEY> for every scalar and list setting, e.g.
+// CLIFY_CACHE_TTL for cache.ttl. Lists are comma-separated.
+func envSettings() (settings, error) {
+	values := settings{}
+	for key, typ := range configFields() {
+		name := envName(key)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		var value any
+		var err error
+		switch typ.Kind() {
+		case reflect.Bool:
+			value, err = strconv.ParseBool(raw)
+		case reflect.Int:
+			value, err = strconv.Atoi(raw)
+		case reflect.Float64:
+			value, err = strconv.ParseFloat(raw, 64)
+		case reflect.Slice:
+			var list []any
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			value = list
+		default:
+			value = raw
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// envName is the variable that sets key, e.g. CLIFY_CACHE_MAX_ENTRIES
+func envName(key string) string {
+	return "CLIFY_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configFields maps the key of every scalar and list field of the config
+// to its type. Profiles are left out.
+func configFields() map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "-" || name == "" {
+				continue
+			}
+			switch field.Type.Kind() {
+			case reflect.Struct:
+				walk(field.Type, prefix+name+".")
+			case reflect.Map, reflect.Pointer:
+			default:
+				fields[prefix+name] = field.Type
+			}
+		}
+	}
+	walk(reflect.TypeOf(models.Config{}), "")
+	return fields
+}
+
+// projectForbidden are settings a project file cannot set: secrets, where
+// queries and the key are sent, and sources of trusted commands
+var projectForbidden = []string{
+	"api_key", "api_key_command", "credential_store",
+	"provider", "endpoint", "fixtures",
+	"encryption", "profiles", "snippets",
+}
+
+// policyKeys are the settings a project file may only tighten
+var policyKeys = []string{"budget.action", "budget.monthly", "context.allow", "context.enabled", "verify_flags"}
+
+// projectRestriction says why a project file may not set key to value over
+// current, or returns "" if it may. Policy may only be tightened.
+func projectRestriction(key string, value, current any) string {
+	for _, forbidden := range projectForbidden {
+		if key == forbidden || strings.HasPrefix(key, forbidden+".") {
+			return "project files cannot set secrets or where queries are sent"
+		}
+	}
+	for _, policy := range policyKeys {
+		// e.g. "budget: null", which would replace every budget setting
+		if strings.HasPrefix(policy, key+".") {
+			return "project files cannot weaken policy"
+		}
+	}
+
+	weaker := false
+	switch key {
+	case "budget.monthly":
+		limit, _ := toFloat(current)
+		v, ok := toFloat(value)
+		weaker = !ok || (limit > 0 && (v <= 0 || v > limit))
+	case "budget.action":
+		weaker = current == BudgetActionBlock && value != BudgetActionBlock
+	case "context.enabled":
+		weaker = value == true && current != true
+	case "context.allow":
+		// Sending more of the environment than the user allowed
+		weaker = !subset(allowList(value), allowList(current))
+	case "verify_flags":
+		weaker = current == true && value != true
+	}
+	if weaker {
+		return "project files cannot weaken policy"
+	}
+	return ""
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// allowList returns a context.allow setting; empty means the default fields
+func allowList(v any) []any {
+	list, _ := v.([]any)
+	if len(list) > 0 {
+		return list
+	}
+	for _, field := range envinfo.DefaultFields {
+		list = append(list, field)
+	}
+	return list
+}
+
+// subset reports whether every item of items is in allowed
+func subset(items, allowed []any) bool {
+	for _, item := range items {
+		found := false
+		for _, other := range allowed {
+			found = found || item == other
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// toSettings flattens v, a struct with yaml tags
+func toSettings(v any) (settings, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	values := settings{}
+	flatten("", tree, values)
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]any, out settings) {
+	for key, value := range tree {
+		if sub, ok := value.(map[string]any); ok {
+			flatten(prefix+key+".", sub, out)
+			continue
+		}
+		out[prefix+key] = value
+	}
+}
+
+// decodeSettings builds a config from flattened settings. A nil value
+// leaves the setting unset.
+func decodeSettings(values settings) (*models.Config, error) {
+	tree := map[string]any{}
+	for _, key := range sortedKeys(values) {
+		value := values[key]
+		if value == nil {
+			continue
+		}
+		node := tree
+		parts := strings.Split(key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			sub, ok := node[part].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				node[part] = sub
+			}
+			node = sub
+		}
+		node[parts[len(parts)-1]] = value
+	}
+
+	cfg := &models.Config{}
+	data, err := yaml.Marshal(tree)
+	if err == nil {
+		err = yaml.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return defaultConfig(), fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+func sortedKeys(values settings) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"clify/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// layersHome sets up a home with an XDG and a ~/.clify config file and a
+// project file above the working directory, and returns the home directory
+func layersHome(t *testing.T, xdg, home, project string) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv(ProfileEnv, "")
+
+	xdgPath := filepath.Join(dir, ".config", "clify", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(xdgPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(xdgPath, []byte(xdg), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeTestConfig(t, dir, home, 0600)
+
+	cwd := filepath.Join(dir, "src", "app", "cmd")
+	if err := os.MkdirAll(cwd, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "app", ProjectFile), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(cwd)
+	return dir
+}
+
+func origins(r *Resolution) map[string]string {
+	m := map[string]string{}
+	for _, s := range r.Settings {
+		m[s.Key] = s.Origin
+	}
+	return m
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	home := layersHome(t,
+		"model: xdg-model\nhistory_size: 7\ncache:\n  ttl: 1h\n",
+		"model: home-model\ncache:\n  max_entries: 50\n",
+		"model: project-model\ntarget:\n  shell: fish\n")
+	t.Setenv("CLIFY_CACHE_TTL", "2h")
+	t.Setenv("CLIFY_CONTEXT_ALLOW", "shell, cwd")
+	SetTargetOverride(models.Target{Shell: "zsh"})
+	defer SetTargetOverride(models.Target{})
+
+	r, err := Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := r.Config
+	if cfg.Model != "project-model" || cfg.HistorySize != 7 || cfg.Cache.MaxEntries != 50 || cfg.Cache.MaxSize != "10MB" {
+		t.Errorf("model %q, history %d, cache %+v: want each from its layer", cfg.Model, cfg.HistorySize, cfg.Cache)
+	}
+	if cfg.Cache.TTL != "2h" || strings.Join(cfg.Context.Allow, ",") != "shell,cwd" || cfg.Target.Shell != "zsh" {
+		t.Errorf("ttl %q, allow %v, shell %q: want the environment and flag values", cfg.Cache.TTL, cfg.Context.Allow, cfg.Target.Shell)
+	}
+
+	want := map[string]string{
+		"model":             filepath.Join(home, "src", "app", ProjectFile),
+		"history_size":      filepath.Join(home, ".config", "clify", "config.yaml"),
+		"cache.max_entries": filepath.Join(home, DefaultCacheDir, "config.yaml"),
+		"cache.max_size":    OriginDefault,
+		"cache.ttl":         "env CLIFY_CACHE_TTL",
+		"target.shell":      "flag --shell",
+	}
+	got := origins(r)
+	for key, origin := range want {
+		if got[key] != origin {
+			t.Errorf("origin of %s = %q, want %q", key, got[key], origin)
+		}
+	}
+
+	t.Setenv("CLIFY_HISTORY_SIZE", "lots")
+	if _, err := Resolve(); err == nil || !strings.Contains(err.Error(), "CLIFY_HISTORY_SIZE") {
+		t.Errorf("Resolve() with a malformed variable: error = %v", err)
+	}
+}
+
+func TestProjectFileCannotSetSecretsOrWeakenPolicy(t *testing.T) {
+	layersHome(t, "",
+		"budget:\n  monthly: 10\n  action: block\nverify_flags: true\ncontext:\n  enabled: true\n  allow: [shell]\n",
+		`api_key: sk-ant-project-key
+api_key_command: cat /tmp/key
+endpoint: http://attacker.example
+encryption:
+  enabled: false
+profiles:
+  evil:
+    dirs: ["/**"]
+budget:
+  monthly: 50
+  action: warn
+verify_flags: false
+context:
+  allow: [shell, cwd, git]
+model: project-model
+`)
+
+	r, err := Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := r.Config
+	if cfg.APIKey != "" || cfg.APIKeyCommand != "" || cfg.Endpoint != "" || len(cfg.Profiles) != 0 || cfg.Profile != "" {
+		t.Errorf("project secrets applied: %+v", cfg)
+	}
+	if cfg.Budget != (models.BudgetConfig{Monthly: 10, Action: BudgetActionBlock}) || !cfg.VerifyFlags || len(cfg.Context.Allow) != 1 {
+		t.Errorf("project weakened policy: budget %+v, verify %v, allow %v", cfg.Budget, cfg.VerifyFlags, cfg.Context.Allow)
+	}
+	if cfg.Model != "project-model" {
+		t.Errorf("model = %q, want the project's", cfg.Model)
+	}
+	if len(r.Ignored) != 9 {
+		t.Errorf("Ignored = %q, want 9 settings", r.Ignored)
+	}
+
+	// Nulls would otherwise replace every budget and context setting
+	layersHome(t, "",
+		"budget:\n  monthly: 10\n  action: block\ncontext:\n  enabled: true\n  allow: [shell]\n",
+		"budget: null\ncontext: null\nverify_flags: null\n")
+	for range 20 {
+		r, err := Resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := r.Config
+		if cfg.Budget != (models.BudgetConfig{Monthly: 10, Action: BudgetActionBlock}) || !cfg.Context.Enabled || len(cfg.Context.Allow) != 1 {
+			t.Fatalf("project nulls applied: budget %+v, context %+v", cfg.Budget, cfg.Context)
+		}
+		if len(r.Ignored) != 2 {
+			t.Fatalf("Ignored = %q, want budget and context", r.Ignored)
+		}
+	}
+
+	// Tightening is allowed
+	layersHome(t, "", "budget:\n  monthly: 10\n  action: warn\n", "budget:\n  monthly: 2\n  action: block\nverify_flags: true\n")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Budget != (models.BudgetConfig{Monthly: 2, Action: BudgetActionBlock}) || !cfg.VerifyFlags {
+		t.Errorf("budget %+v, verify %v: want the project's stricter policy", cfg.Budget, cfg.VerifyFlags)
+	}
+}
+
+func TestRetiredModelMeansTheDefault(t *testing.T) {
+	layersHome(t, "", "model: claude-3-sonnet-20240229\n", "")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Model != DefaultModel {
+		t.Errorf("Model = %q, want the default %q", cfg.Model, DefaultModel)
+	}
+}
+
+func TestProjectPolicyAppliesOverProfile(t *testing.T) {
+	home := layersHome(t, "",
+		"budget:\n  monthly: 10\nprofiles:\n  work:\n    budget: {monthly: 50, action: warn}\n",
+		"budget:\n  monthly: 20\n  action: block\n")
+	SetProfileOverride("work")
+	defer SetProfileOverride("")
+	SetOfflineOverride(true)
+	defer SetOfflineOverride(false)
+
+	r, err := Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 20 is looser than the user's 10 but stricter than the profile's 50
+	if r.Config.Budget != (models.BudgetConfig{Monthly: 20, Action: BudgetActionBlock}) || len(r.Ignored) != 0 {
+		t.Errorf("budget %+v, ignored %q: want the project's stricter budget", r.Config.Budget, r.Ignored)
+	}
+	if !r.Config.Offline {
+		t.Error("Offline = false, want the flag's")
+	}
+
+	want := map[string]string{
+		"budget.monthly": filepath.Join(home, "src", "app", ProjectFile),
+		"profile":        "flag --profile",
+		"offline":        "flag --offline",
+	}
+	got := origins(r)
+	for key, origin := range want {
+		if got[key] != origin {
+			t.Errorf("origin of %s = %q, want %q", key, got[key], origin)
+		}
+	}
+}
+
+func TestUpdateConfigKeepsComments(t *testing.T) {
+	home := layersHome(t, "api_key: sk-ant-old-xdg-key\n", "# my settings\nmodel: m # pinned\napi_key: sk-ant-old-key\n", "")
+
+	if err := UpdateConfig(map[string]any{"api_key": nil, "encryption.enabled": true}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(home, DefaultCacheDir, "config.yaml"))
+	want := "# my settings\nmodel: m # pinned\nencryption:\n  enabled: true\n"
+	if string(data) != want {
+		t.Errorf("config file = %q, want %q", data, want)
+	}
+	// Removed from every user file, and nothing else written
+	data, _ = os.ReadFile(filepath.Join(home, ".config", "clify", "config.yaml"))
+	if len(data) != 0 {
+		t.Errorf("XDG config file = %q, want the key removed", data)
+	}
+}
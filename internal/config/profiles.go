@@ -0,0 +1,114 @@
+package config
+
+import (
+	"clify/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfileEnv selects a profile when --profile is not given
+const ProfileEnv = "CLIFY_PROFILE"
+
+// profileOverride is the profile chosen with --profile
+var profileOverride string
+
+// SetProfileOverride makes every later LoadConfig use the named profile
+func SetProfileOverride(name string) {
+	profileOverride = name
+}
+
+// ProfileNames lists the configured profiles in order
+func ProfileNames(cfg *models.Config) []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectProfile returns the profile named by --profile or $CLIFY_PROFILE,
+// or else the one whose dirs match cwd most closely; "" if none applies
+func selectProfile(cfg *models.Config, cwd string) (string, error) {
+	name := profileOverride
+	if name == "" {
+		name = os.Getenv(ProfileEnv)
+	}
+	if name != "" {
+		if _, ok := cfg.Profiles[name]; !ok {
+			return "", fmt.Errorf("unknown profile %q (configured: %s)", name, strings.Join(ProfileNames(cfg), ", "))
+		}
+		return name, nil
+	}
+
+	// The profile matching the deepest directory wins, e.g. ~/work/oss/**
+	// over ~/work/**
+	best := -1
+	for _, candidate := range ProfileNames(cfg) {
+		for _, pattern := range cfg.Profiles[candidate].Dirs {
+			if depth := matchDir(ExpandHome(pattern), cwd); depth > best {
+				name, best = candidate, depth
+			}
+		}
+	}
+	return name, nil
+}
+
+// profileOrigin says what selected the profile name
+func profileOrigin(name string) string {
+	switch {
+	case profileOverride != "":
+		return "flag --profile"
+	case os.Getenv(ProfileEnv) != "":
+		return "env " + ProfileEnv
+	}
+	return "profiles." + name + ".dirs"
+}
+
+// matchDir reports how deep a directory matching pattern contains dir, or
+// -1. "base/**" matches base and everything below it; other patterns match
+// dir or one of its parents with filepath.Match.
+func matchDir(pattern, dir string) int {
+	dir = filepath.Clean(dir)
+	pattern = filepath.FromSlash(pattern)
+	if base, ok := strings.CutSuffix(pattern, string(filepath.Separator)+"**"); ok {
+		base = filepath.Clean(base)
+		if dir == base || strings.HasPrefix(dir, base+string(filepath.Separator)) {
+			return len(base)
+		}
+		return -1
+	}
+
+	pattern = filepath.Clean(pattern)
+	for d := dir; ; d = filepath.Dir(d) {
+		if ok, _ := filepath.Match(pattern, d); ok {
+			return len(d)
+		}
+		if filepath.Dir(d) == d {
+			return -1
+		}
+	}
+}
+
+// credentialProfile is the profile whose own API key cfg uses, or ""
+func credentialProfile(cfg *models.Config) string {
+	if cfg.Profile != "" && cfg.Profiles[cfg.Profile].CredentialStore != "" {
+		return cfg.Profile
+	}
+	return ""
+}
+
+// ExpandHome replaces a leading ~ in path with the home directory
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"clify/internal/models"
+	"path/filepath"
+	"testing"
+)
+
+const profilesConfig = `model: default-model
+budget:
+  monthly: 5
+  action: warn
+profiles:
+  work:
+    dirs: ["~/work/**"]
+    model: work-model
+    api_key_env: WORK_API_KEY
+    budget:
+      monthly: 50
+      action: block
+    target:
+      os: linux
+  oss:
+    dirs: ["~/work/oss/*"]
+    model: oss-model
+    verify_flags: true
+`
+
+func TestProfileSelection(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ProfileEnv, "")
+	t.Setenv("WORK_API_KEY", "sk-work")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-default")
+	writeTestConfig(t, home, profilesConfig, 0600)
+	cfg, err := loadUserConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cwd, flag, env string
+		want           string
+	}{
+		{cwd: filepath.Join(home, "personal"), want: ""},
+		{cwd: filepath.Join(home, "work"), want: "work"},
+		{cwd: filepath.Join(home, "work", "api", "cmd"), want: "work"},
+		// The deeper match wins over ~/work/**
+		{cwd: filepath.Join(home, "work", "oss", "clify", "internal"), want: "oss"},
+		{cwd: filepath.Join(home, "personal"), env: "work", want: "work"},
+		{cwd: filepath.Join(home, "work"), flag: "oss", env: "work", want: "oss"},
+	}
+	for _, tt := range tests {
+		SetProfileOverride(tt.flag)
+		t.Setenv(ProfileEnv, tt.env)
+		if got, err := selectProfile(cfg, tt.cwd); err != nil || got != tt.want {
+			t.Errorf("selectProfile(%s, flag %q, env %q) = %q, %v, want %q", tt.cwd, tt.flag, tt.env, got, err, tt.want)
+		}
+	}
+	SetProfileOverride("")
+
+	t.Setenv(ProfileEnv, "missing")
+	if _, err := selectProfile(cfg, home); err == nil {
+		t.Error("selectProfile() with an unknown profile succeeded")
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("WORK_API_KEY", "sk-work")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-default")
+	t.Chdir(t.TempDir())
+	writeTestConfig(t, home, profilesConfig+"target:\n  shell: zsh\n", 0600)
+	SetProfileOverride("work")
+	defer SetProfileOverride("")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile != "work" || cfg.Model != "work-model" || cfg.APIKey != "sk-work" {
+		t.Errorf("with profile work: profile %q, model %q, key %q", cfg.Profile, cfg.Model, cfg.APIKey)
+	}
+	if cfg.Budget.Monthly != 50 || cfg.Budget.Action != BudgetActionBlock {
+		t.Errorf("budget = %+v, want the profile's", cfg.Budget)
+	}
+	// Fields the profile leaves unset are inherited
+	if cfg.Target != (models.Target{OS: "linux", Shell: "zsh"}) {
+		t.Errorf("target = %+v, want the profile's OS and the configured shell", cfg.Target)
+	}
+}
+
+func TestProfileOverridesOnlyItsNestedKeys(t *testing.T) {
+	layersHome(t, "",
+		"budget: {monthly: 5, action: block}\ncontext: {enabled: true, allow: [shell]}\nprofiles:\n  work:\n    budget: {monthly: 50}\n    context: {allow: [cwd]}\n",
+		"")
+	SetProfileOverride("work")
+	defer SetProfileOverride("")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Budget != (models.BudgetConfig{Monthly: 50, Action: BudgetActionBlock}) {
+		t.Errorf("budget = %+v, want the profile's limit and the configured action", cfg.Budget)
+	}
+	if !cfg.Context.Enabled || len(cfg.Context.Allow) != 1 || cfg.Context.Allow[0] != "cwd" {
+		t.Errorf("context = %+v, want the profile's allow list, still enabled", cfg.Context)
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"clify/internal/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultUsageFile  = "usage.json"
+	BudgetActionWarn  = "warn"
+	BudgetActionBlock = "block"
+)
+
+// UsageTotal sums token usage and cost over a period
+type UsageTotal struct {
+	Period       string
+	Queries      int
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64
+}
+
+type UsageTracker struct {
+	filePath string
+	records  []models.Usage
+	profile  string
+	readOnly error // why the file must not be overwritten
+
+	warnedUnpriced bool
+}
+
+func NewUsageTracker() *UsageTracker {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+
+	ut := &UsageTracker{
+		filePath: filepath.Join(home, DefaultCacheDir, DefaultUsageFile),
+	}
+
+	if err := ut.load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load usage: %v\n", err)
+	}
+	return ut
+}
+
+// SetProfile makes Record tag usage with the named configuration profile
+// and MonthToDate count only that profile's, so that each profile's budget
+// applies to its own spend. "" is usage without a profile.
+func (ut *UsageTracker) SetProfile(name string) {
+	ut.profile = name
+}
+
+// Record appends a usage record and persists it
+func (ut *UsageTracker) Record(usage models.Usage) error {
+	if usage.Unpriced && !ut.warnedUnpriced {
+		ut.warnedUnpriced = true
+		fmt.Fprintf(os.Stderr, "Warning: no price known for model %s; its cost is not counted, and a blocking budget stops further calls\n", usage.Model)
+	}
+	usage.Profile = ut.profile
+	ut.records = append(ut.records, usage)
+	return ut.save()
+}
+
+// MonthToDate returns the profile's total cost since the start of the
+// current month
+func (ut *UsageTracker) MonthToDate(now time.Time) float64 {
+	var total float64
+	for _, record := range ut.thisMonth(now) {
+		total += record.CostUSD
+	}
+	return total
+}
+
+// UnpricedModel returns a model of the profile's calls this month whose
+// price is unknown, or ""
+func (ut *UsageTracker) UnpricedModel(now time.Time) string {
+	for _, record := range ut.thisMonth(now) {
+		if record.Unpriced {
+			return record.Model
+		}
+	}
+	return ""
+}
+
+// CheckBudget returns an error when budget blocks further API calls. A
+// blocking budget fails closed once calls to a model without a known price
+// make this month's spend unknown.
+func (ut *UsageTracker) CheckBudget(budget models.BudgetConfig, now time.Time) error {
+	if budget.Action == BudgetActionBlock && budget.Monthly > 0 {
+		if model := ut.UnpricedModel(now); model != "" {
+			return fmt.Errorf("monthly budget of $%.2f cannot be enforced: the price of %s is unknown; set budget.action to warn to keep using it", budget.Monthly, model)
+		}
+	}
+	return CheckBudget(budget, ut.MonthToDate(now))
+}
+
+// thisMonth returns the profile's records since the start of now's month
+func (ut *UsageTracker) thisMonth(now time.Time) []models.Usage {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var records []models.Usage
+	for _, record := range ut.records {
+		if record.Profile == ut.profile && !record.Timestamp.Before(start) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// CheckBudget returns an error when budget blocks further API calls after
+// spent this month. A warn budget, or none, never blocks.
+func CheckBudget(budget models.BudgetConfig, spent float64) error {
+	if budget.Action != BudgetActionBlock || !BudgetExceeded(budget, spent) {
+		return nil
+	}
+	return fmt.Errorf("monthly budget of $%.2f exceeded ($%.2f spent); raise budget.monthly or set budget.action to warn", budget.Monthly, spent)
+}
+
+// BudgetExceeded reports whether spent reaches the monthly limit, if any
+func BudgetExceeded(budget models.BudgetConfig, spent float64) bool {
+	return budget.Monthly > 0 && spent >= budget.Monthly
+}
+
+// Daily returns per-day totals, oldest first
+func (ut *UsageTracker) Daily() []UsageTotal {
+	return ut.totals("2006-01-02")
+}
+
+// Monthly returns per-month totals, oldest first
+func (ut *UsageTracker) Monthly() []UsageTotal {
+	return ut.totals("2006-01")
+}
+
+func (ut *UsageTracker) totals(layout string) []UsageTotal {
+	byPeriod := make(map[string]*UsageTotal)
+	for _, record := range ut.records {
+		period := record.Timestamp.Local().Format(layout)
+		total, ok := byPeriod[period]
+		if !ok {
+			total = &UsageTotal{Period: period}
+			byPeriod[period] = total
+		}
+		total.Queries++
+		total.InputTokens += record.InputTokens
+		total.OutputTokens += record.OutputTokens
+		total.CostUSD += record.CostUSD
+	}
+
+	totals := make([]UsageTotal, 0, len(byPeriod))
+	for _, total := range byPeriod {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Period < totals[j].Period
+	})
+	return totals
+}
+
+func (ut *UsageTracker) load() error {
+	data, err := os.ReadFile(ut.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // No usage recorded yet
+		}
+		return fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if data, err = unseal(data); err != nil {
+		ut.readOnly = fmt.Errorf("cannot read usage file: %w", err)
+		return ut.readOnly
+	}
+
+	if err := json.Unmarshal(data, &ut.records); err != nil {
+		return fmt.Errorf("failed to unmarshal usage data: %w", err)
+	}
+	return nil
+}
+
+func (ut *UsageTracker) save() error {
+	if ut.readOnly != nil {
+		return ut.readOnly
+	}
+	if err := os.MkdirAll(filepath.Dir(ut.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(ut.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+	if data, err = seal(data); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(ut.filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	return nil
+}
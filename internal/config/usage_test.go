@@ -0,0 +1,101 @@
+package config
+
+import (
+	"clify/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonthToDate(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	tracker := &UsageTracker{records: []models.Usage{
+		{Timestamp: time.Date(2026, 9, 30, 23, 59, 59, 0, loc), CostUSD: 1},
+		{Timestamp: time.Date(2026, 10, 1, 0, 0, 0, 0, loc), CostUSD: 2},
+		{Timestamp: time.Date(2026, 10, 18, 12, 0, 0, 0, loc), CostUSD: 4},
+		// 23:30 UTC on the 30th is already October in UTC+2
+		{Timestamp: time.Date(2026, 9, 30, 23, 30, 0, 0, time.UTC), CostUSD: 8},
+	}}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want float64
+	}{
+		{"first instant of the month", time.Date(2026, 10, 1, 0, 0, 0, 0, loc), 14},
+		{"mid month", time.Date(2026, 10, 19, 9, 0, 0, 0, loc), 14},
+		// The month starts at midnight in now's zone
+		{"in UTC", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tracker.MonthToDate(tt.now); got != tt.want {
+				t.Errorf("MonthToDate(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		budget    models.BudgetConfig
+		spent     float64
+		exceeded  bool
+		wantBlock bool
+	}{
+		{"no budget", models.BudgetConfig{Action: BudgetActionBlock}, 100, false, false},
+		{"warn under", models.BudgetConfig{Monthly: 10, Action: BudgetActionWarn}, 5, false, false},
+		{"warn over", models.BudgetConfig{Monthly: 10, Action: BudgetActionWarn}, 12, true, false},
+		{"action defaults to warn", models.BudgetConfig{Monthly: 10}, 12, true, false},
+		{"block under", models.BudgetConfig{Monthly: 10, Action: BudgetActionBlock}, 9.99, false, false},
+		{"block at limit", models.BudgetConfig{Monthly: 10, Action: BudgetActionBlock}, 10, true, true},
+		{"block over", models.BudgetConfig{Monthly: 10, Action: BudgetActionBlock}, 12, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BudgetExceeded(tt.budget, tt.spent); got != tt.exceeded {
+				t.Errorf("BudgetExceeded() = %v, want %v", got, tt.exceeded)
+			}
+			if err := CheckBudget(tt.budget, tt.spent); (err != nil) != tt.wantBlock {
+				t.Errorf("CheckBudget() error = %v, want blocked %v", err, tt.wantBlock)
+			}
+		})
+	}
+}
+
+func TestMonthToDateCountsTheProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Now()
+	tracker := NewUsageTracker()
+	tracker.Record(models.Usage{Timestamp: now, CostUSD: 1})
+	tracker.SetProfile("work")
+	tracker.Record(models.Usage{Timestamp: now, CostUSD: 2})
+
+	tracker = NewUsageTracker()
+	if got := tracker.MonthToDate(now); got != 1 {
+		t.Errorf("MonthToDate() without a profile = %v, want 1", got)
+	}
+	tracker.SetProfile("work")
+	if got := tracker.MonthToDate(now); got != 2 {
+		t.Errorf("MonthToDate() for work = %v, want 2", got)
+	}
+}
+
+func TestCheckBudgetFailsClosedForUnpricedModels(t *testing.T) {
+	now := time.Now()
+	tracker := &UsageTracker{records: []models.Usage{
+		{Model: "custom-model", Unpriced: true, Timestamp: now},
+	}}
+
+	block := models.BudgetConfig{Monthly: 10, Action: BudgetActionBlock}
+	if err := tracker.CheckBudget(block, now); err == nil || !strings.Contains(err.Error(), "custom-model") {
+		t.Errorf("CheckBudget() with an unpriced call = %v, want an error naming the model", err)
+	}
+	if err := tracker.CheckBudget(models.BudgetConfig{Monthly: 10, Action: BudgetActionWarn}, now); err != nil {
+		t.Errorf("CheckBudget() with a warn budget = %v, want nil", err)
+	}
+	if err := tracker.CheckBudget(block, now.AddDate(0, 1, 0)); err != nil {
+		t.Errorf("CheckBudget() the next month = %v, want nil", err)
+	}
+}
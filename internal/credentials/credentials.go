@@ -0,0 +1,183 @@
+// Package credentials keeps the API key out of the config file: in the OS
+// keyring, in an encrypted file, or behind a command such as a password
+// manager's CLI.
+package credentials
+
+import (
+	"bytes"
+	"clify/internal/crypt"
+	"clify/internal/keyring"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Backend names
+const (
+	BackendKeyring = "keyring"
+	BackendFile    = "file"
+	BackendCommand = "command"
+)
+
+// File is the encrypted file BackendFile keeps the default key in
+const File = "credentials"
+
+// Account is the keyring account of the default key, or of a profile's own
+func Account(profile string) string {
+	if profile == "" {
+		return "api-key"
+	}
+	return "api-key/" + profile
+}
+
+// FileName is the encrypted file of the default key, or of a profile's own
+func FileName(profile string) string {
+	if profile == "" {
+		return File
+	}
+	return File + "-" + profile
+}
+
+// ErrNotFound means the backend holds no API key
+var ErrNotFound = errors.New("no API key stored")
+
+// Backend stores the API key
+type Backend interface {
+	Name() string
+	Get() (string, error)
+	Set(apiKey string) error
+	Delete() error
+}
+
+// Keyring keeps the key in the macOS keychain or the Secret Service
+type Keyring struct {
+	Account string
+}
+
+func (Keyring) Name() string { return BackendKeyring }
+
+func (k Keyring) Get() (string, error) {
+	secret, err := keyring.Get(k.Account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return secret, err
+}
+
+func (k Keyring) Set(apiKey string) error {
+	return keyring.Set(k.Account, "clify API key ("+k.Account+")", apiKey)
+}
+
+// Available reports whether the OS keyring can be used here
+func (k Keyring) Available() bool {
+	_, err := keyring.Get(k.Account)
+	return !errors.Is(err, keyring.ErrUnavailable)
+}
+
+func (k Keyring) Delete() error {
+	err := keyring.Delete(k.Account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// EncryptedFile keeps the key in a 0600 file encrypted with the key Key
+// returns
+type EncryptedFile struct {
+	Path string
+	Key  func() (crypt.Key, error)
+}
+
+func (f EncryptedFile) Name() string { return BackendFile }
+
+func (f EncryptedFile) Get() (string, error) {
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", f.Path, err)
+	}
+	key, err := f.Key()
+	if err != nil {
+		return "", err
+	}
+	apiKey, err := crypt.Open(key, data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return string(apiKey), nil
+}
+
+func (f EncryptedFile) Set(apiKey string) error {
+	key, err := f.Key()
+	if err != nil {
+		return err
+	}
+	data, err := crypt.Seal(key, []byte(apiKey))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, f.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+func (f EncryptedFile) Delete() error {
+	if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Command prints the key, e.g. "op read op://Private/Anthropic/credential".
+// It is read only; the password manager owns the key.
+type Command struct {
+	Command string
+}
+
+func (c Command) Name() string { return BackendCommand }
+
+func (c Command) Get() (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", c.Command)
+	} else {
+		cmd = exec.Command("sh", "-c", c.Command)
+	}
+	var stdout bytes.Buffer
+	// Password managers may ask to be unlocked
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, &stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("api_key_command failed: %w", err)
+	}
+
+	apiKey := strings.TrimSpace(stdout.String())
+	if apiKey == "" {
+		return "", errors.New("api_key_command printed nothing")
+	}
+	return apiKey, nil
+}
+
+func (c Command) Set(string) error {
+	return errors.New("the API key comes from api_key_command; update it in your password manager")
+}
+
+func (c Command) Delete() error {
+	return c.Set("")
+}
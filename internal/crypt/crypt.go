@@ -0,0 +1,94 @@
+// Package crypt encrypts clify's local files at rest with AES-256-GCM and
+// manages the key, which is derived from a passphrase or kept in the OS
+// keyring or a key file.
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of a key in bytes
+const KeySize = 32
+
+// prefix marks sealed data. Sealed data is a single line of text, so a
+// JSON lines file can be sealed line by line and still be appended to.
+const prefix = "clify-enc1:"
+
+// ErrDecrypt means sealed data was not written with the key, or was damaged
+var ErrDecrypt = errors.New("cannot decrypt: wrong key or damaged data")
+
+// Key is an AES-256 key
+type Key []byte
+
+// NewKey returns a random key
+func NewKey() (Key, error) {
+	key := make(Key, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// IsSealed reports whether data was written by Seal
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(prefix))
+}
+
+// Seal encrypts plaintext with key
+func Seal(key Key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(prefix))
+
+	out := make([]byte, len(prefix)+base64.RawStdEncoding.EncodedLen(len(sealed)))
+	copy(out, prefix)
+	base64.RawStdEncoding.Encode(out[len(prefix):], sealed)
+	return out, nil
+}
+
+// Open decrypts data written by Seal
+func Open(key Key, data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return nil, errors.New("data is not encrypted")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, base64.RawStdEncoding.DecodedLen(len(data)-len(prefix)))
+	n, err := base64.RawStdEncoding.Decode(sealed, bytes.TrimSpace(data[len(prefix):]))
+	if err != nil || n < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	sealed = sealed[:n]
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(prefix))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key length %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,79 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte(`{"query":"ssh into db01.internal"}`)
+
+	sealed, err := Seal(key, plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("db01")) || bytes.ContainsRune(sealed, '\n') {
+		t.Errorf("Seal() = %q, want a single opaque line", sealed)
+	}
+	if got, err := Open(key, sealed); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open() = %q, %v, want the plaintext", got, err)
+	}
+
+	other, _ := NewKey()
+	if _, err := Open(other, sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() with another key error = %v, want ErrDecrypt", err)
+	}
+	if _, err := Open(key, sealed[:len(sealed)-4]); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() of truncated data error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestFileKey(t *testing.T) {
+	dir := t.TempDir()
+
+	key, source, err := CreateKey(dir, SourceFile)
+	if err != nil || source != SourceFile {
+		t.Fatalf("CreateKey() = %v, %v", source, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, KeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("key file mode = %o, want 600", perm)
+	}
+
+	// An existing key is reused, never replaced
+	again, _, err := CreateKey(dir, SourceFile)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Errorf("second CreateKey() = %v, want the same key", err)
+	}
+	if loaded, err := LoadKey(dir, SourceFile); err != nil || !bytes.Equal(loaded, key) {
+		t.Errorf("LoadKey() = %v, want the created key", err)
+	}
+}
+
+func TestPassphraseKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	key, _, err := CreateKey(dir, SourcePassphrase)
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+	if loaded, err := LoadKey(dir, SourcePassphrase); err != nil || !bytes.Equal(loaded, key) {
+		t.Errorf("LoadKey() = %v, want the same key from the same passphrase", err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := LoadKey(dir, SourcePassphrase); err == nil {
+		t.Error("LoadKey() with a wrong passphrase succeeded")
+	}
+}
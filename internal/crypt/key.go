@@ -0,0 +1,223 @@
+package crypt
+
+import (
+	"clify/internal/keyring"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Where the key comes from
+const (
+	SourcePassphrase = "passphrase" // derived from a passphrase
+	SourceKeyring    = "keyring"    // random, kept in the OS keyring
+	SourceFile       = "file"       // random, kept in a 0600 key file
+)
+
+const (
+	// KeyFile holds the key for SourceFile
+	KeyFile = "encryption.key"
+	// ParamsFile holds the salt and a check value for SourcePassphrase
+	ParamsFile = "encryption.json"
+
+	// DefaultIterations is the PBKDF2-SHA256 work factor for new passphrases
+	DefaultIterations = 600000
+
+	// keyringAccount is the keyring item for SourceKeyring
+	keyringAccount = "encryption-key"
+
+	// checkText is sealed with a passphrase key to tell a wrong passphrase
+	checkText = "clify"
+)
+
+// params are the key derivation parameters for a passphrase
+type params struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Check      string `json:"check"`
+}
+
+// ParseSource checks a configured key source. Empty means SourceKeyring.
+func ParseSource(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", SourceKeyring:
+		return SourceKeyring, nil
+	case SourcePassphrase:
+		return SourcePassphrase, nil
+	case SourceFile:
+		return SourceFile, nil
+	}
+	return "", fmt.Errorf("unknown key source %q (want passphrase, keyring or file)", s)
+}
+
+// DeriveKey derives a key from a passphrase
+func DeriveKey(passphrase string, salt []byte, iterations int) (Key, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key, nil
+}
+
+// LoadKey returns the existing key from source, keeping its files in dir
+func LoadKey(dir, source string) (Key, error) {
+	switch source {
+	case SourcePassphrase:
+		return loadPassphraseKey(dir)
+	case SourceKeyring:
+		encoded, err := keyring.Get(keyringAccount)
+		if err != nil {
+			return nil, err
+		}
+		return decodeKey(encoded)
+	case SourceFile:
+		return loadKeyFile(filepath.Join(dir, KeyFile))
+	}
+	return nil, fmt.Errorf("unknown key source %q", source)
+}
+
+// CreateKey returns the key from source, creating it if there is none yet.
+// When the OS keyring cannot be used, e.g. on a headless Linux server, the
+// key goes to a key file instead; the source used is returned.
+func CreateKey(dir, source string) (Key, string, error) {
+	if key, err := LoadKey(dir, source); err == nil {
+		return key, source, nil
+	} else if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, keyring.ErrNotFound) && !errors.Is(err, keyring.ErrUnavailable) {
+		return nil, "", err
+	}
+
+	if source == SourcePassphrase {
+		key, err := createPassphraseKey(dir)
+		return key, source, err
+	}
+
+	key, err := NewKey()
+	if err != nil {
+		return nil, "", err
+	}
+	if source == SourceKeyring {
+		err := keyring.Set(keyringAccount, "clify encryption key", base64.StdEncoding.EncodeToString(key))
+		if err == nil {
+			return key, source, nil
+		}
+		fmt.Fprintf(os.Stderr, "Warning: OS keyring unavailable (%v); storing the key in %s\n", err, filepath.Join(dir, KeyFile))
+	}
+
+	path := filepath.Join(dir, KeyFile)
+	if err := writeNew(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n")); err != nil {
+		return nil, "", err
+	}
+	return key, SourceFile, nil
+}
+
+func loadKeyFile(path string) (Key, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s is readable by other users; run chmod 600 %s\n", path, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return decodeKey(string(data))
+}
+
+func decodeKey(encoded string) (Key, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != KeySize {
+		return nil, errors.New("stored encryption key is malformed")
+	}
+	return key, nil
+}
+
+func loadPassphraseKey(dir string) (Key, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ParamsFile))
+	if err != nil {
+		return nil, err
+	}
+	var p params
+	if err := json.Unmarshal(data, &p); err != nil || len(p.Salt) == 0 || p.Iterations <= 0 {
+		return nil, fmt.Errorf("malformed %s", ParamsFile)
+	}
+
+	passphrase, err := ReadPassphrase("Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	key, err := DeriveKey(passphrase, p.Salt, p.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := Open(key, []byte(p.Check)); err != nil || string(check) != checkText {
+		return nil, errors.New("wrong passphrase")
+	}
+	return key, nil
+}
+
+func createPassphraseKey(dir string) (Key, error) {
+	passphrase, err := ReadPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	if os.Getenv(PassphraseEnv) == "" {
+		again, err := ReadPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if again != passphrase {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+
+	p := params{Salt: make([]byte, 16), Iterations: DefaultIterations}
+	if _, err := rand.Read(p.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := DeriveKey(passphrase, p.Salt, p.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	check, err := Seal(key, []byte(checkText))
+	if err != nil {
+		return nil, err
+	}
+	p.Check = string(check)
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key parameters: %w", err)
+	}
+	if err := writeNew(filepath.Join(dir, ParamsFile), data); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// writeNew creates path with mode 0600, never replacing an existing key
+func writeNew(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,46 @@
+package crypt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// PassphraseEnv supplies the passphrase without a prompt, e.g. in scripts
+const PassphraseEnv = "CLIFY_PASSPHRASE"
+
+// ReadPassphrase returns $CLIFY_PASSPHRASE, or asks for the passphrase on
+// the terminal without echoing it
+func ReadPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		// Windows has no /dev/tty; use the console if stdin is one
+		if !term.IsTerminal(os.Stdin.Fd()) {
+			return "", fmt.Errorf("no terminal to ask for the passphrase; set %s", PassphraseEnv)
+		}
+		tty = os.Stdin
+	} else {
+		defer tty.Close()
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	var line []byte
+	if term.IsTerminal(tty.Fd()) {
+		line, err = term.ReadPassword(tty.Fd())
+		fmt.Fprintln(os.Stderr)
+	} else {
+		line, err = bufio.NewReader(tty).ReadBytes('\n')
+	}
+	if err != nil && len(line) == 0 {
+		return "", errors.New("failed to read passphrase")
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
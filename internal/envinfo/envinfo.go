@@ -0,0 +1,264 @@
+package envinfo
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Field names accepted in the context allow-list
+const (
+	FieldShell          = "shell"
+	FieldCwd            = "cwd"
+	FieldGit            = "git"
+	FieldTools          = "tools"
+	FieldCoreutils      = "coreutils"
+	FieldDistro         = "distro"
+	FieldPackageManager = "package_manager"
+)
+
+// AllFields lists every field the collector knows about
+var AllFields = []string{
+	FieldShell,
+	FieldCwd,
+	FieldGit,
+	FieldTools,
+	FieldCoreutils,
+	FieldDistro,
+	FieldPackageManager,
+}
+
+// DefaultFields is used when context is enabled without an explicit allow-list.
+// The working directory is left out since it can reveal user and project names.
+var DefaultFields = []string{
+	FieldShell,
+	FieldGit,
+	FieldTools,
+	FieldCoreutils,
+	FieldDistro,
+	FieldPackageManager,
+}
+
+// relevantTools are the optional tools worth telling the model about
+var relevantTools = []string{"rg", "fd", "jq", "docker", "podman", "kubectl", "fzf"}
+
+// packageManagers in order of preference when several are installed
+var packageManagers = []string{"brew", "port", "apt", "dnf", "yum", "pacman", "zypper", "apk", "nix", "winget", "choco", "scoop"}
+
+// Info holds the local environment details that may be sent with a query
+type Info struct {
+	Shell          string
+	ShellVersion   string
+	Cwd            string
+	GitRepo        bool
+	Tools          []string
+	Coreutils      string // "GNU" or "BSD"
+	Distro         string
+	PackageManager string
+
+	fields map[string]bool
+}
+
+// Collect gathers the environment details named in allow. Fields that are not
+// allowed are never inspected.
+func Collect(allow []string) *Info {
+	if len(allow) == 0 {
+		allow = DefaultFields
+	}
+
+	info := &Info{fields: make(map[string]bool)}
+	for _, field := range allow {
+		info.fields[strings.ToLower(strings.TrimSpace(field))] = true
+	}
+
+	if info.fields[FieldShell] {
+		info.Shell, info.ShellVersion = detectShell()
+	}
+	if info.fields[FieldCwd] {
+		if cwd, err := os.Getwd(); err == nil {
+			info.Cwd = cwd
+		}
+	}
+	if info.fields[FieldGit] {
+		info.GitRepo = inGitRepo()
+	}
+	if info.fields[FieldTools] {
+		info.Tools = detectTools()
+	}
+	if info.fields[FieldCoreutils] {
+		info.Coreutils = detectCoreutils()
+	}
+	if info.fields[FieldDistro] {
+		info.Distro = detectDistro()
+	}
+	if info.fields[FieldPackageManager] {
+		info.PackageManager = DetectPackageManager()
+	}
+
+	return info
+}
+
+// Lines renders the collected details as prompt lines, one per field
+func (i *Info) Lines() []string {
+	if i == nil {
+		return nil
+	}
+
+	var lines []string
+	if i.fields[FieldShell] && i.Shell != "" {
+		shell := i.Shell
+		if i.ShellVersion != "" {
+			shell += " " + i.ShellVersion
+		}
+		lines = append(lines, "Shell: "+shell)
+	}
+	if i.fields[FieldCwd] && i.Cwd != "" {
+		lines = append(lines, "Working Directory: "+i.Cwd)
+	}
+	if i.fields[FieldGit] {
+		if i.GitRepo {
+			lines = append(lines, "Git Repository: yes")
+		} else {
+			lines = append(lines, "Git Repository: no")
+		}
+	}
+	if i.fields[FieldTools] {
+		tools := "none"
+		if len(i.Tools) > 0 {
+			tools = strings.Join(i.Tools, ", ")
+		}
+		lines = append(lines, "Installed Tools: "+tools)
+	}
+	if i.fields[FieldCoreutils] && i.Coreutils != "" {
+		lines = append(lines, "Coreutils: "+i.Coreutils)
+	}
+	if i.fields[FieldDistro] && i.Distro != "" {
+		lines = append(lines, "Distribution: "+i.Distro)
+	}
+	if i.fields[FieldPackageManager] && i.PackageManager != "" {
+		lines = append(lines, "Package Manager: "+i.PackageManager)
+	}
+	return lines
+}
+
+// DetectPackageManager returns the first known package manager found on $PATH
+func DetectPackageManager() string {
+	for _, pm := range packageManagers {
+		if _, err := exec.LookPath(pm); err == nil {
+			return pm
+		}
+	}
+	return ""
+}
+
+func detectShell() (string, string) {
+	shellPath := os.Getenv("SHELL")
+	if shellPath == "" {
+		if runtime.GOOS == "windows" {
+			return "powershell", ""
+		}
+		return "", ""
+	}
+
+	name := filepath.Base(shellPath)
+	out, err := exec.Command(shellPath, "--version").Output()
+	if err != nil {
+		return name, ""
+	}
+	return name, parseShellVersion(string(out))
+}
+
+// parseShellVersion picks the first version-looking word from --version output
+func parseShellVersion(output string) string {
+	firstLine, _, _ := strings.Cut(output, "\n")
+	for _, word := range strings.Fields(firstLine) {
+		word = strings.TrimPrefix(word, "v")
+		if word != "" && word[0] >= '0' && word[0] <= '9' && strings.Contains(word, ".") {
+			// Drop build suffixes such as "5.2.15(1)-release"
+			if idx := strings.IndexAny(word, "(-,"); idx > 0 {
+				word = word[:idx]
+			}
+			return word
+		}
+	}
+	return ""
+}
+
+func inGitRepo() bool {
+	dir, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+func detectTools() []string {
+	var found []string
+	for _, tool := range relevantTools {
+		if _, err := exec.LookPath(tool); err == nil {
+			found = append(found, tool)
+		}
+	}
+	return found
+}
+
+func detectCoreutils() string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	// GNU tools understand --version, BSD ones reject it
+	if err := exec.Command("ls", "--version").Run(); err == nil {
+		return "GNU"
+	}
+	return "BSD"
+}
+
+func detectDistro() string {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/etc/os-release")
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		return parseOSRelease(bufio.NewScanner(f))
+	case "darwin":
+		out, err := exec.Command("sw_vers", "-productVersion").Output()
+		if err != nil {
+			return ""
+		}
+		return "macOS " + strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+// parseOSRelease extracts PRETTY_NAME (or NAME) from an os-release file
+func parseOSRelease(scanner *bufio.Scanner) string {
+	var name string
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "PRETTY_NAME":
+			return value
+		case "NAME":
+			name = value
+		}
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package envinfo
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseShellVersion(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{"GNU bash, version 5.2.15(1)-release (x86_64-pc-linux-gnu)\n", "5.2.15"},
+		{"zsh 5.9 (x86_64-apple-darwin23.0)\n", "5.9"},
+		{"fish, version 3.7.1\n", "3.7.1"},
+		{"no version here\n", ""},
+	}
+
+	for _, tt := range tests {
+		result := parseShellVersion(tt.output)
+		if result != tt.expected {
+			t.Errorf("parseShellVersion(%q) = %q, want %q", tt.output, result, tt.expected)
+		}
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	data := "NAME=\"Ubuntu\"\nVERSION_ID=\"24.04\"\nPRETTY_NAME=\"Ubuntu 24.04 LTS\"\n"
+	result := parseOSRelease(bufio.NewScanner(strings.NewReader(data)))
+	if result != "Ubuntu 24.04 LTS" {
+		t.Errorf("parseOSRelease() = %q, want %q", result, "Ubuntu 24.04 LTS")
+	}
+}
+
+func TestLinesOnlyIncludesAllowedFields(t *testing.T) {
+	info := &Info{
+		Shell:   "zsh",
+		Cwd:     "/home/user/secret-project",
+		GitRepo: true,
+		fields:  map[string]bool{FieldShell: true, FieldGit: true},
+	}
+
+	for _, line := range info.Lines() {
+		if strings.Contains(line, "secret-project") {
+			t.Errorf("Lines() leaked cwd although it is not allowed: %q", line)
+		}
+	}
+	if got := len(info.Lines()); got != 2 {
+		t.Errorf("len(Lines()) = %d, want 2", got)
+	}
+}
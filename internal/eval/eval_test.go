@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"bytes"
+	"clify/internal/client"
+	"clify/internal/client/clienttest"
+	"clify/internal/config"
+	"clify/internal/models"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	c := Case{
+		Query: "find all .txt files",
+		Expect: Expectations{
+			Contains:    []string{"find"},
+			NotContains: []string{"rm "},
+			MaxSafety:   "safe",
+			POSIXOnly:   true,
+		},
+		OS: map[string]Expectations{
+			"darwin": {Contains: []string{"mdfind"}},
+		},
+	}
+
+	good := &models.Response{Commands: []models.Command{
+		{Text: "find . -name '*.txt'", SafetyLevel: "safe"},
+	}}
+	bad := &models.Response{Commands: []models.Command{
+		{Text: "fd -e txt | xargs rm -f", SafetyLevel: "warning"},
+	}}
+
+	if score, failures := Score(c, "linux", good); score != 1 || len(failures) != 0 {
+		t.Errorf("Score(good, linux) = %v %v, want 1 and no failures", score, failures)
+	}
+	if score, _ := Score(c, "darwin", good); score != 0.8 {
+		t.Errorf("Score(good, darwin) = %v, want 0.8 with the extra OS check", score)
+	}
+	if score, failures := Score(c, "linux", bad); score != 0 || len(failures) != 4 {
+		t.Errorf("Score(bad, linux) = %v %v, want 0 and 4 failures", score, failures)
+	}
+}
+
+func TestRunWithReplayAndReport(t *testing.T) {
+	dir := t.TempDir()
+	fixture := client.Fixture{
+		Request:  client.Request{Query: "show date", Schema: client.CommandResponseSchema()},
+		Response: clienttest.MessageBody(`{"explanation": "Date", "commands": [{"text": "date", "description": "Print the date"}]}`, 10, 5),
+	}
+	if err := client.SaveFixture(dir, fixture); err != nil {
+		t.Fatalf("SaveFixture() error = %v", err)
+	}
+
+	suite := &Suite{Name: "test", Cases: []Case{
+		{Query: "show date", Expect: Expectations{Contains: []string{"date"}, MaxSafety: "safe"}},
+		{Query: "missing fixture", Expect: Expectations{MinCommands: 1}},
+	}}
+
+	runner := NewRunner(client.NewClaudeClientWithProvider(client.NewReplayProvider(dir)), "linux")
+	run := runner.Run(context.Background(), suite)
+
+	if run.Passed() != 1 || run.Score != 0.5 {
+		t.Errorf("Run() passed %d with score %v, want 1 and 0.5", run.Passed(), run.Score)
+	}
+
+	previous := &Run{Suite: "test", Score: 1, Results: []CaseResult{
+		{Query: "show date", Score: 1},
+		{Query: "missing fixture", Score: 1},
+	}}
+
+	var out bytes.Buffer
+	WriteReport(&out, run, previous)
+	if !strings.Contains(out.String(), "regressed from 1.00") {
+		t.Errorf("WriteReport() does not mark the regression:\n%s", out.String())
+	}
+}
+
+func TestRunTracksUsageAndBudget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	fixture := client.Fixture{
+		Request:  client.Request{Query: "show date", Schema: client.CommandResponseSchema()},
+		Response: clienttest.MessageBody(`{"explanation": "Date", "commands": [{"text": "date", "description": "Print the date"}]}`, 1000, 500),
+	}
+	if err := client.SaveFixture(dir, fixture); err != nil {
+		t.Fatalf("SaveFixture() error = %v", err)
+	}
+	suite := &Suite{Name: "test", Cases: []Case{
+		{Query: "show date", Expect: Expectations{Contains: []string{"date"}}},
+		{Query: "show date", Expect: Expectations{Contains: []string{"date"}}},
+	}}
+
+	tracker := config.NewUsageTracker()
+	runner := NewRunner(client.NewClaudeClientWithProvider(client.NewReplayProvider(dir)), "linux")
+	runner.TrackUsage(tracker, models.BudgetConfig{Monthly: 1e-9, Action: config.BudgetActionBlock})
+	run := runner.Run(context.Background(), suite)
+
+	// The first case spends the budget; the second is blocked
+	if len(tracker.Monthly()) != 1 || tracker.Monthly()[0].Queries != 1 {
+		t.Errorf("usage = %+v, want the first case recorded", tracker.Monthly())
+	}
+	if run.Results[0].Error != "" || !strings.Contains(run.Results[1].Error, "budget") {
+		t.Errorf("results = %+v, want the second case blocked by the budget", run.Results)
+	}
+}
+
+func TestDefaultSuiteParses(t *testing.T) {
+	suite, err := DefaultSuite()
+	if err != nil {
+		t.Fatalf("DefaultSuite() error = %v", err)
+	}
+	if len(suite.Cases) == 0 {
+		t.Error("DefaultSuite() has no cases")
+	}
+}
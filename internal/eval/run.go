@@ -0,0 +1,210 @@
+package eval
+
+import (
+	"clify/internal/client"
+	"clify/internal/config"
+	"clify/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// CaseResult is the outcome of a single case
+type CaseResult struct {
+	Query    string   `json:"query"`
+	Score    float64  `json:"score"`
+	Failures []string `json:"failures,omitempty"`
+	Commands []string `json:"commands,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Passed reports whether every check of the case passed
+func (r CaseResult) Passed() bool {
+	return r.Error == "" && len(r.Failures) == 0
+}
+
+// Run is the stored result of running a suite
+type Run struct {
+	Suite     string       `json:"suite"`
+	OS        string       `json:"os"`
+	Timestamp time.Time    `json:"timestamp"`
+	Score     float64      `json:"score"`
+	Results   []CaseResult `json:"results"`
+}
+
+// Passed counts the cases in which every check passed
+func (r *Run) Passed() int {
+	passed := 0
+	for _, result := range r.Results {
+		if result.Passed() {
+			passed++
+		}
+	}
+	return passed
+}
+
+// Runner evaluates suites against a client
+type Runner struct {
+	client *client.ClaudeClient
+	goos   string
+	usage  *config.UsageTracker
+	budget models.BudgetConfig
+}
+
+func NewRunner(client *client.ClaudeClient, goos string) *Runner {
+	return &Runner{client: client, goos: goos}
+}
+
+// TrackUsage records the token usage of each case as queries do, and stops
+// calling the API once a blocking monthly budget is exceeded
+func (r *Runner) TrackUsage(usage *config.UsageTracker, budget models.BudgetConfig) {
+	r.usage = usage
+	r.budget = budget
+}
+
+// Run queries every case in suite and scores the responses. Query errors
+// score zero rather than aborting the run.
+func (r *Runner) Run(ctx context.Context, suite *Suite) *Run {
+	run := &Run{
+		Suite:     suite.Name,
+		OS:        r.goos,
+		Timestamp: time.Now(),
+	}
+
+	var total float64
+	for _, c := range suite.Cases {
+		result := CaseResult{Query: c.Query}
+
+		response, err := r.query(ctx, c.Query)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Score, result.Failures = Score(c, r.goos, response)
+			for _, cmd := range response.Commands {
+				result.Commands = append(result.Commands, cmd.Text)
+			}
+		}
+
+		total += result.Score
+		run.Results = append(run.Results, result)
+	}
+
+	if len(run.Results) > 0 {
+		run.Score = total / float64(len(run.Results))
+	}
+	return run
+}
+
+func (r *Runner) query(ctx context.Context, query string) (*models.Response, error) {
+	if r.usage == nil {
+		return r.client.QueryCommands(ctx, query)
+	}
+	if err := r.usage.CheckBudget(r.budget, time.Now()); err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.QueryCommands(ctx, query)
+	if err == nil && response.Usage != nil {
+		record := *response.Usage
+		record.Query = query
+		if err := r.usage.Record(record); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+		}
+	}
+	return response, err
+}
+
+// LoadRun reads a stored run; a missing file returns nil without error
+func LoadRun(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read run: %w", err)
+	}
+
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse run: %w", err)
+	}
+	return &run, nil
+}
+
+// SaveRun writes run to path, creating parent directories
+func SaveRun(path string, run *Run) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+// WriteReport prints run and, when previous is not nil, the per-case change
+func WriteReport(w io.Writer, run, previous *Run) {
+	var before map[string]CaseResult
+	if previous != nil {
+		before = make(map[string]CaseResult, len(previous.Results))
+		for _, result := range previous.Results {
+			before[result.Query] = result
+		}
+	}
+
+	for _, result := range run.Results {
+		mark := "PASS"
+		if !result.Passed() {
+			mark = "FAIL"
+		}
+
+		change := ""
+		if before != nil {
+			prev, ok := before[result.Query]
+			switch {
+			case !ok:
+				change = "  (new)"
+			case result.Score > prev.Score:
+				change = fmt.Sprintf("  (improved from %.2f)", prev.Score)
+			case result.Score < prev.Score:
+				change = fmt.Sprintf("  (regressed from %.2f)", prev.Score)
+			}
+			delete(before, result.Query)
+		}
+
+		fmt.Fprintf(w, "%s %.2f  %s%s\n", mark, result.Score, result.Query, change)
+		if result.Error != "" {
+			fmt.Fprintf(w, "       error: %s\n", result.Error)
+		}
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "       - %s\n", failure)
+		}
+	}
+
+	removed := make([]string, 0, len(before))
+	for query := range before {
+		removed = append(removed, query)
+	}
+	sort.Strings(removed)
+	for _, query := range removed {
+		fmt.Fprintf(w, "GONE       %s  (removed from suite)\n", query)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Suite %q on %s: %d/%d cases passed, score %.2f", run.Suite, run.OS, run.Passed(), len(run.Results), run.Score)
+	if previous != nil {
+		fmt.Fprintf(w, " (previous %.2f, %+.2f)", previous.Score, run.Score-previous.Score)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,95 @@
+package eval
+
+import (
+	"clify/internal/models"
+	"clify/internal/validate"
+	"fmt"
+	"strings"
+)
+
+// posixUtilities are the utilities defined by POSIX.1-2017 that are commonly
+// used in generated commands
+var posixUtilities = map[string]bool{
+	"awk": true, "basename": true, "cat": true, "cd": true, "chgrp": true,
+	"chmod": true, "chown": true, "cksum": true, "cmp": true, "comm": true,
+	"cp": true, "cut": true, "date": true, "dd": true, "df": true, "diff": true,
+	"dirname": true, "du": true, "echo": true, "env": true, "expr": true,
+	"false": true, "file": true, "find": true, "fold": true, "grep": true,
+	"head": true, "id": true, "join": true, "kill": true, "ln": true, "ls": true,
+	"mkdir": true, "mkfifo": true, "mv": true, "nice": true, "nohup": true,
+	"od": true, "paste": true, "pax": true, "printf": true, "ps": true,
+	"pwd": true, "read": true, "rm": true, "rmdir": true, "sed": true,
+	"sh": true, "sleep": true, "sort": true, "split": true,
+	"tail": true, "tee": true, "test": true, "time": true, "touch": true,
+	"tr": true, "true": true, "tty": true, "uname": true, "uniq": true,
+	"wc": true, "xargs": true, "[": true,
+}
+
+// safetyRank orders safety levels from least to most dangerous
+var safetyRank = map[string]int{
+	string(models.SafetyLevelSafe):      0,
+	string(models.SafetyLevelWarning):   1,
+	string(models.SafetyLevelDangerous): 2,
+}
+
+// Score checks response against the case expectations for goos. It returns
+// the fraction of checks passed and a message for every failed check.
+func Score(c Case, goos string, response *models.Response) (float64, []string) {
+	expect := c.expectationsFor(goos)
+
+	var checks int
+	var failures []string
+	check := func(ok bool, format string, args ...interface{}) {
+		checks++
+		if !ok {
+			failures = append(failures, fmt.Sprintf(format, args...))
+		}
+	}
+
+	commands := response.Commands
+
+	if expect.MinCommands > 0 {
+		check(len(commands) >= expect.MinCommands, "expected at least %d commands, got %d", expect.MinCommands, len(commands))
+	}
+
+	for _, want := range expect.Contains {
+		check(anyContains(commands, want), "no command contains %q", want)
+	}
+
+	for _, unwanted := range expect.NotContains {
+		check(!anyContains(commands, unwanted), "a command contains %q", unwanted)
+	}
+
+	if expect.MaxSafety != "" {
+		limit := safetyRank[expect.MaxSafety]
+		for _, cmd := range commands {
+			check(safetyRank[cmd.SafetyLevel] <= limit, "%q is %s, allowed at most %s", cmd.Text, cmd.SafetyLevel, expect.MaxSafety)
+		}
+	}
+
+	if expect.POSIXOnly {
+		for _, cmd := range commands {
+			var nonPOSIX []string
+			for _, name := range validate.Executables(cmd.Text) {
+				if !posixUtilities[name] {
+					nonPOSIX = append(nonPOSIX, name)
+				}
+			}
+			check(len(nonPOSIX) == 0, "%q uses non-POSIX tools: %s", cmd.Text, strings.Join(nonPOSIX, ", "))
+		}
+	}
+
+	if checks == 0 {
+		return 1, nil
+	}
+	return float64(checks-len(failures)) / float64(checks), failures
+}
+
+func anyContains(commands []models.Command, substr string) bool {
+	for _, cmd := range commands {
+		if strings.Contains(cmd.Text, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package eval
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_suite.yaml
+var defaultSuite []byte
+
+// Suite is a named set of queries with expected result properties
+type Suite struct {
+	Name  string `yaml:"name"`
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is a single query and its expectations. OS entries, keyed by GOOS,
+// add expectations that only apply on that operating system.
+type Case struct {
+	Query  string                  `yaml:"query"`
+	Expect Expectations            `yaml:"expect"`
+	OS     map[string]Expectations `yaml:"os,omitempty"`
+}
+
+// Expectations are the properties a response must have
+type Expectations struct {
+	Contains    []string `yaml:"contains,omitempty"`     // each must appear in some command
+	NotContains []string `yaml:"not_contains,omitempty"` // none may appear in any command
+	MaxSafety   string   `yaml:"max_safety,omitempty"`   // "safe" or "warning"
+	POSIXOnly   bool     `yaml:"posix_only,omitempty"`   // only POSIX utilities and builtins
+	MinCommands int      `yaml:"min_commands,omitempty"`
+}
+
+// DefaultSuite returns the suite bundled with clify
+func DefaultSuite() (*Suite, error) {
+	return parseSuite(defaultSuite)
+}
+
+// LoadSuite reads a YAML suite from path
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+	return parseSuite(data)
+}
+
+func parseSuite(data []byte) (*Suite, error) {
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite: %w", err)
+	}
+	if suite.Name == "" {
+		return nil, fmt.Errorf("suite name is required")
+	}
+	for i, c := range suite.Cases {
+		if c.Query == "" {
+			return nil, fmt.Errorf("case %d has no query", i+1)
+		}
+	}
+	return &suite, nil
+}
+
+// expectationsFor merges the base expectations with those for goos
+func (c Case) expectationsFor(goos string) Expectations {
+	merged := c.Expect
+	extra, ok := c.OS[goos]
+	if !ok {
+		return merged
+	}
+
+	merged.Contains = append(append([]string(nil), merged.Contains...), extra.Contains...)
+	merged.NotContains = append(append([]string(nil), merged.NotContains...), extra.NotContains...)
+	if extra.MaxSafety != "" {
+		merged.MaxSafety = extra.MaxSafety
+	}
+	merged.POSIXOnly = merged.POSIXOnly || extra.POSIXOnly
+	if extra.MinCommands > merged.MinCommands {
+		merged.MinCommands = extra.MinCommands
+	}
+	return merged
+}
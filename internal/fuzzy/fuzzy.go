@@ -0,0 +1,134 @@
+// Package fuzzy scores how well short texts such as queries and task
+// descriptions match, tolerating word order, plurals and typos.
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords carry no meaning for matching tasks
+var stopWords = map[string]bool{
+	"a": true, "all": true, "an": true, "and": true, "by": true, "current": true,
+	"do": true, "for": true, "from": true, "how": true, "i": true, "in": true,
+	"into": true, "is": true, "it": true, "me": true, "my": true, "of": true,
+	"on": true, "that": true, "the": true, "this": true, "to": true, "what": true,
+	"with": true,
+}
+
+// Tokens splits text into lower-case words without punctuation, stop words
+// or common plural and -ing endings
+func Tokens(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if stopWords[field] {
+			continue
+		}
+		tokens = append(tokens, stem(field))
+	}
+	return tokens
+}
+
+func stem(word string) string {
+	switch {
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		return strings.TrimSuffix(word, "ing")
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	default:
+		return word
+	}
+}
+
+// Coverage returns the fraction of query words found in text, from 0 to 1.
+// Words match exactly, by prefix or with a single typo.
+func Coverage(query, text string) float64 {
+	return coverage(Tokens(query), Tokens(text))
+}
+
+// Similarity is a symmetric match score from 0 to 1: the harmonic mean of
+// the coverage of a by b and of b by a
+func Similarity(a, b string) float64 {
+	ta, tb := Tokens(a), Tokens(b)
+	ab, ba := coverage(ta, tb), coverage(tb, ta)
+	if ab+ba == 0 {
+		return 0
+	}
+	return 2 * ab * ba / (ab + ba)
+}
+
+func coverage(query, text []string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, q := range query {
+		best := 0.0
+		for _, t := range text {
+			if score := wordScore(q, t); score > best {
+				best = score
+			}
+		}
+		total += best
+	}
+	return total / float64(len(query))
+}
+
+func wordScore(a, b string) float64 {
+	switch {
+	case a == b:
+		return 1
+	case len(a) >= 3 && len(b) >= 3 && (strings.HasPrefix(a, b) || strings.HasPrefix(b, a)):
+		return 0.8
+	case len(a) >= 4 && len(b) >= 4 && withinOneEdit(a, b):
+		return 0.7
+	default:
+		return 0
+	}
+}
+
+// withinOneEdit reports whether a and b differ by at most one insertion,
+// deletion, substitution or swap of adjacent letters
+func withinOneEdit(a, b string) bool {
+	if len(a) == len(b) {
+		for i := 0; i+1 < len(a); i++ {
+			if a[i] != b[i] {
+				if a[i] == b[i+1] && a[i+1] == b[i] && a[i+2:] == b[i+2:] {
+					return true
+				}
+				break
+			}
+		}
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+
+	i, j, edits := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		if len(a) == len(b) {
+			i++
+		}
+		j++
+	}
+	return edits+(len(b)-j)-(len(a)-i) <= 1
+}
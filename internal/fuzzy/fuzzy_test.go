@@ -0,0 +1,55 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	got := Tokens("How do I find all the .TXT files, recursively?")
+	want := []string{"find", "txt", "file", "recursively"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens() = %q, want %q", got, want)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"list files", "List files!", 1, 1},
+		{"list files", "lsit files", 0.8, 0.9},
+		{"compress a directory", "compress directory with tar", 0.7, 0.9},
+		{"list files", "kill process", 0, 0},
+		{"", "list files", 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %.2f, want between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestWithinOneEdit(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"files", "files", true},
+		{"files", "file", true},
+		{"files", "fines", true},
+		{"files", "flies", true},
+		{"files", "filse", true},
+		{"files", "fliez", false},
+		{"files", "fil", false},
+	}
+
+	for _, tt := range tests {
+		if got := withinOneEdit(tt.a, tt.b); got != tt.want {
+			t.Errorf("withinOneEdit(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
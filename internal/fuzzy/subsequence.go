@@ -0,0 +1,94 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Subsequence matches pattern as typed in an incremental search: each
+// space-separated term must appear in text with its letters in order, not
+// necessarily adjacent. It returns a score that favors runs of adjacent
+// letters and word starts, and the rune positions in text that matched, in
+// ascending order. An empty pattern matches everything with score 0.
+func Subsequence(pattern, text string) (score int, positions []int, ok bool) {
+	runes := []rune(strings.ToLower(text))
+	for _, term := range strings.Fields(strings.ToLower(pattern)) {
+		termScore, termPositions, found := matchTerm([]rune(term), runes)
+		if !found {
+			return 0, nil, false
+		}
+		score += termScore
+		positions = mergePositions(positions, termPositions)
+	}
+	return score, positions, true
+}
+
+// matchTerm finds the shortest window of text ending at the first complete
+// match of term, which keeps letters close together, and scores it
+func matchTerm(term, text []rune) (int, []int, bool) {
+	// Forward: where the leftmost greedy match ends
+	end, t := -1, 0
+	for i, r := range text {
+		if r == term[t] {
+			t++
+			if t == len(term) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Backward: the latest start that still matches up to end
+	positions := make([]int, len(term))
+	t = len(term) - 1
+	for i := end; i >= 0 && t >= 0; i-- {
+		if text[i] == term[t] {
+			positions[t] = i
+			t--
+		}
+	}
+
+	score := 0
+	for k, pos := range positions {
+		score++
+		if pos == 0 || !isWordRune(text[pos-1]) {
+			score += 3
+		}
+		if k > 0 {
+			if gap := pos - positions[k-1] - 1; gap == 0 {
+				score += 2
+			} else {
+				score -= min(gap, 3)
+			}
+		}
+	}
+	return score, positions, true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// mergePositions returns the sorted union of two sorted position lists
+func mergePositions(a, b []int) []int {
+	merged := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			merged = append(merged, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			merged = append(merged, b[j])
+			j++
+		default:
+			merged = append(merged, a[i])
+			i++
+			j++
+		}
+	}
+	return merged
+}
@@ -0,0 +1,34 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubsequence(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		positions     []int
+		ok            bool
+	}{
+		{"dsk", "show disk usage", []int{5, 7, 8}, true},
+		{"DU", "du -sh *", []int{0, 1}, true},
+		{"use disk", "show disk usage", []int{5, 6, 7, 8, 10, 11, 14}, true},
+		{"xyz", "show disk usage", nil, false},
+		{"", "anything", nil, true},
+	}
+
+	for _, tt := range tests {
+		_, positions, ok := Subsequence(tt.pattern, tt.text)
+		if ok != tt.ok || !reflect.DeepEqual(positions, tt.positions) {
+			t.Errorf("Subsequence(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.text, positions, ok, tt.positions, tt.ok)
+		}
+	}
+
+	// Adjacent letters and word starts beat scattered ones
+	tight, _, _ := Subsequence("disk", "show disk usage")
+	loose, _, _ := Subsequence("disk", "edit the search key")
+	if tight <= loose {
+		t.Errorf("Subsequence scores tight %d <= loose %d", tight, loose)
+	}
+}
@@ -0,0 +1,119 @@
+// Package keyring stores secrets in the macOS keychain or the Secret
+// Service (GNOME Keyring, KWallet) through their command-line tools.
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Service is the keyring service every clify item is stored under
+const Service = "clify"
+
+var (
+	// ErrUnavailable means this system has no keyring clify can use, e.g. a
+	// headless Linux server without a Secret Service
+	ErrUnavailable = errors.New("no supported OS keyring")
+	// ErrNotFound means the keyring has no item for the account
+	ErrNotFound = errors.New("not found in the keyring")
+)
+
+// Get returns the secret stored for account
+func Get(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", Service, "-a", account, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", Service, "account", account)
+	default:
+		return "", ErrUnavailable
+	}
+
+	out, err := run(cmd)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(out)
+	if secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores secret for account, replacing an existing one
+func Set(account, label, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security prompts for -w without a value on the terminal, not
+		// stdin, so the command goes to its interactive mode on stdin
+		// instead, which keeps the secret out of ps. Not yet tried on a Mac.
+		if strings.ContainsAny(secret, "\r\n") {
+			return errors.New("secret must be a single line")
+		}
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(addCommand(account, label, secret))
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "store", "--label="+label, "service", Service, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		return ErrUnavailable
+	}
+	_, err := run(cmd)
+	return err
+}
+
+// addCommand is the line that makes security -i store secret for account
+func addCommand(account, label, secret string) string {
+	args := []string{"add-generic-password", "-U", "-s", Service, "-a", account, "-l", label, "-w", secret}
+	for i, arg := range args {
+		arg = strings.ReplaceAll(arg, `\`, `\\`)
+		args[i] = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+	}
+	return strings.Join(args, " ") + "\n"
+}
+
+// Delete removes the secret stored for account
+func Delete(account string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "delete-generic-password", "-s", Service, "-a", account)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "clear", "service", Service, "account", account)
+	default:
+		return ErrUnavailable
+	}
+	_, err := run(cmd)
+	return err
+}
+
+// run runs a keyring tool. A failure without a message means the item is
+// missing; anything else, such as no D-Bus session, means the keyring
+// cannot be used.
+func run(cmd *exec.Cmd) (string, error) {
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return "", fmt.Errorf("%w: %s not found", ErrUnavailable, cmd.Args[0])
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		if msg == "" || strings.Contains(msg, "could not be found") {
+			// macOS says "The specified item could not be found in the keychain."
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("%w: %s: %s", ErrUnavailable, cmd.Args[0], msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,11 @@
+package keyring
+
+import "testing"
+
+func TestAddCommand(t *testing.T) {
+	got := addCommand("anthropic", `clify "work" key`, `sk-a\b"c`)
+	want := `"add-generic-password" "-U" "-s" "clify" "-a" "anthropic" "-l" "clify \"work\" key" "-w" "sk-a\\b\"c"` + "\n"
+	if got != want {
+		t.Errorf("addCommand() = %q, want %q", got, want)
+	}
+}
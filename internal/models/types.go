@@ -1,34 +1,223 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
 // Response represents the AI response with commands and explanation
 type Response struct {
-	Explanation string    `json:"explanation"`
-	Commands    []Command `json:"commands"`
+	Explanation    string    `json:"explanation"`
+	Commands       []Command `json:"commands"`
+	Usage          *Usage    `json:"usage,omitempty"`           // set only for fresh API responses
+	RepairAttempts int       `json:"repair_attempts,omitempty"` // repair requests sent for this response
+	Offline        bool      `json:"offline,omitempty"`         // answered without the API
+}
+
+// Usage records the tokens consumed by a single API call
+type Usage struct {
+	Query        string    `json:"query,omitempty"`
+	Profile      string    `json:"profile,omitempty"` // the configuration profile, "" for none
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+	Unpriced     bool      `json:"unpriced,omitempty"` // the model's price is unknown, so CostUSD is 0
+	Timestamp    time.Time `json:"timestamp"`
 }
 
 // Command represents a single executable command
 type Command struct {
+	Text          string   `json:"text"`
+	Description   string   `json:"description"`
+	Prerequisites []string `json:"prerequisites,omitempty"` // tools or packages needed
+	Warnings      []string `json:"warnings,omitempty"`      // side effects reported by the model
+	Confidence    float64  `json:"confidence,omitempty"`    // 0-1, as reported by the model
+	Platforms     []string `json:"platforms,omitempty"`
+	Docs          []string `json:"docs,omitempty"` // man page sections or links
+	SafetyLevel   string   `json:"safety_level"`   // "safe", "warning", "dangerous"
+	MissingTools  []string `json:"missing_tools,omitempty"`
+	FlagWarnings  []string `json:"flag_warnings,omitempty"`
+	Repaired      bool     `json:"repaired,omitempty"`     // fixed by a repair request
+	SyntaxError   string   `json:"syntax_error,omitempty"` // still invalid after repair
+	Source        string   `json:"source,omitempty"`       // where an offline command came from
+}
+
+// Workflow is an ordered sequence of steps that together accomplish a task
+type Workflow struct {
+	Explanation    string         `json:"explanation"`
+	Steps          []WorkflowStep `json:"steps"`
+	Usage          *Usage         `json:"usage,omitempty"` // set only for fresh API responses
+	RepairAttempts int            `json:"repair_attempts,omitempty"`
+}
+
+// WorkflowStep is a single command in a workflow
+type WorkflowStep struct {
+	ID          int    `json:"id"`
 	Text        string `json:"text"`
 	Description string `json:"description"`
-	SafetyLevel string `json:"safety_level"` // "safe", "warning", "dangerous"
+	DependsOn   []int  `json:"depends_on,omitempty"`
+	SafetyLevel string `json:"safety_level"`
 }
 
 // CacheEntry represents a cached query and response
 type CacheEntry struct {
+	CacheScope
 	Query     string    `json:"query"`
 	Response  string    `json:"response"`
 	Timestamp time.Time `json:"timestamp"`
+	LastUsed  time.Time `json:"last_used,omitzero"` // last served from the cache
+	Pinned    bool      `json:"pinned,omitempty"`   // never expires or is evicted
+}
+
+// HistoryEntry is a query the user asked, where, and the command they chose
+type HistoryEntry struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Query     string    `json:"query"`
+	Cwd       string    `json:"cwd,omitempty"`
+	Command   string    `json:"command,omitempty"` // the command selected from the results
+}
+
+// Favorite is a command saved to the personal library with the query it answered
+type Favorite struct {
+	Command     string    `yaml:"command" json:"command"`
+	Query       string    `yaml:"query,omitempty" json:"query,omitempty"`
+	Description string    `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags        []string  `yaml:"tags,omitempty" json:"tags,omitempty"`
+	SafetyLevel string    `yaml:"safety_level" json:"safety_level"`
+	Added       time.Time `yaml:"added" json:"added"`
+}
+
+// CacheScope holds every input besides the query that shapes an answer.
+// Entries only match lookups with an identical scope.
+type CacheScope struct {
+	Kind   string `json:"kind,omitempty"` // "workflow", or empty for commands
+	Model  string `json:"model,omitempty"`
+	OS     string `json:"os,omitempty"`
+	Shell  string `json:"shell,omitempty"`
+	Distro string `json:"distro,omitempty"`
+	Prompt string `json:"prompt,omitempty"` // hash of the prompt templates
+	Schema string `json:"schema,omitempty"` // hash of the response schema
+}
+
+// String joins the scope dimensions into a stable key prefix
+func (s CacheScope) String() string {
+	return strings.Join([]string{s.Kind, s.Model, s.OS, s.Shell, s.Distro, s.Prompt, s.Schema}, "|")
 }
 
 // Config represents application configuration
 type Config struct {
-	APIKey    string `yaml:"api_key"`
-	CacheFile string `yaml:"cache_file"`
-	Model     string `yaml:"model"`
+	APIKey      string        `yaml:"api_key,omitempty"` // plaintext; clify setup moves it to CredentialStore
+	CacheFile   string        `yaml:"cache_file"`
+	Model       string        `yaml:"model"`
+	Context     ContextConfig `yaml:"context"`
+	VerifyFlags bool          `yaml:"verify_flags"` // check flags against man pages and --help
+	Offline     bool          `yaml:"offline"`      // answer from the cache and the bundled catalog only
+	Budget      BudgetConfig  `yaml:"budget"`
+	Provider    string        `yaml:"provider,omitempty"` // "anthropic", "replay" or "record"
+	Endpoint    string        `yaml:"endpoint,omitempty"` // override the messages API URL
+	Fixtures    string        `yaml:"fixtures,omitempty"` // directory for replay/record fixtures
+	Target      Target        `yaml:"target,omitempty"`   // default target system
+	// RepairAttempts bounds repair requests for invalid responses; 0 disables them
+	RepairAttempts int `yaml:"repair_attempts"`
+	// SimilarityThreshold (0-1) at which a similar past query is offered; 0 disables it
+	SimilarityThreshold float64     `yaml:"similarity_threshold"`
+	Cache               CacheConfig `yaml:"cache"`
+	// HistorySize is the number of queries kept in the history; 0 disables it
+	HistorySize int            `yaml:"history_size"`
+	Snippets    SnippetsConfig `yaml:"snippets,omitempty"`
+	// APIKeyCommand prints the API key, e.g. a password manager's CLI
+	APIKeyCommand string `yaml:"api_key_command,omitempty"`
+	// CredentialStore is where clify setup stored the API key: "keyring" or "file"
+	CredentialStore string `yaml:"credential_store,omitempty"`
+	// Encryption encrypts the cache, history and usage log at rest
+	Encryption EncryptionConfig `yaml:"encryption,omitempty"`
+	// Prompts is a directory of prompt template overrides, applied after
+	// ~/.clify/prompts and before the project's .clify/prompts
+	Prompts string `yaml:"prompts,omitempty"`
+	// Profiles are named sets of overrides, e.g. for work and personal projects
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	// Profile is the active profile, chosen by --profile, $CLIFY_PROFILE or
+	// the working directory
+	Profile string `yaml:"-"`
+}
+
+// Profile overrides the settings it sets. It is selected automatically in
+// working directories matching one of Dirs, e.g. "~/work/**".
+type Profile struct {
+	Dirs     []string `yaml:"dirs,omitempty"`
+	Provider string   `yaml:"provider,omitempty"`
+	Model    string   `yaml:"model,omitempty"`
+	Endpoint string   `yaml:"endpoint,omitempty"`
+	// The API key is read from an environment variable, printed by a
+	// command, or stored by "clify --profile NAME setup"
+	APIKeyEnv       string `yaml:"api_key_env,omitempty"`
+	APIKeyCommand   string `yaml:"api_key_command,omitempty"`
+	CredentialStore string `yaml:"credential_store,omitempty"`
+	// Policy
+	Budget      *BudgetConfig  `yaml:"budget,omitempty"`
+	Context     *ContextConfig `yaml:"context,omitempty"`
+	VerifyFlags *bool          `yaml:"verify_flags,omitempty"`
+	Target      Target         `yaml:"target,omitempty"`
+	Prompts     string         `yaml:"prompts,omitempty"`
+}
+
+// EncryptionConfig turns on encryption at rest. KeySource is "passphrase",
+// "keyring" or "file".
+type EncryptionConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	KeySource string `yaml:"key_source,omitempty"`
+}
+
+// SnippetsConfig lists directories of team snippet files, e.g. checkouts of
+// a shared repository
+type SnippetsConfig struct {
+	Sources []string `yaml:"sources,omitempty"`
+}
+
+// CacheConfig bounds the response cache. TTL is a duration such as "24h" or
+// "7d", or "never"; zero limits mean unlimited.
+type CacheConfig struct {
+	TTL        string `yaml:"ttl"`
+	MaxEntries int    `yaml:"max_entries"`
+	MaxSize    string `yaml:"max_size"` // e.g. "10MB"
+}
+
+// BudgetConfig sets a monthly spending limit in USD
+type BudgetConfig struct {
+	Monthly float64 `yaml:"monthly"`
+	Action  string  `yaml:"action"` // "warn" or "block"
+}
+
+// Target describes the system generated commands are meant for. Empty fields
+// mean the local system.
+type Target struct {
+	OS     string `yaml:"os,omitempty" json:"os,omitempty"`
+	Shell  string `yaml:"shell,omitempty" json:"shell,omitempty"`
+	Distro string `yaml:"distro,omitempty" json:"distro,omitempty"`
+}
+
+// IsLocal reports whether no target field is overridden
+func (t Target) IsLocal() bool {
+	return t.OS == "" && t.Shell == "" && t.Distro == ""
+}
+
+// String joins the overridden fields, e.g. "linux/bash/debian"
+func (t Target) String() string {
+	var parts []string
+	for _, part := range []string{t.OS, t.Shell, t.Distro} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// ContextConfig controls which local environment details are sent with queries
+type ContextConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Allow   []string `yaml:"allow,omitempty"`
 }
 
 // SafetyLevel represents the safety classification of a command
@@ -56,4 +245,4 @@ type TutorialStep struct {
 	Description string
 	Example     string
 	Action      string
-}
\ No newline at end of file
+}
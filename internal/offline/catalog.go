@@ -0,0 +1,86 @@
+package offline
+
+import (
+	"clify/internal/fuzzy"
+	_ "embed"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog.yaml
+var defaultCatalog []byte
+
+// Catalog is a curated set of commands for common tasks
+type Catalog struct {
+	Entries []Entry `yaml:"entries"`
+}
+
+// Entry is one task with the commands that accomplish it
+type Entry struct {
+	Task     string    `yaml:"task"`
+	Keywords []string  `yaml:"keywords,omitempty"`
+	Commands []Command `yaml:"commands"`
+}
+
+// Command is a command for a task. OS lists the GOOS names it works
+// on; an empty list means every Unix-like system.
+type Command struct {
+	Text        string   `yaml:"text"`
+	Description string   `yaml:"description"`
+	OS          []string `yaml:"os,omitempty"`
+}
+
+// Match is a catalog entry that matches a query
+type Match struct {
+	Entry    Entry
+	Commands []Command // the commands for the requested OS
+	Score    float64
+}
+
+// DefaultCatalog returns the catalog bundled with clify
+func DefaultCatalog() (*Catalog, error) {
+	var catalog Catalog
+	if err := yaml.Unmarshal(defaultCatalog, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog: %w", err)
+	}
+	return &catalog, nil
+}
+
+// Search returns entries with commands for goos whose task matches query
+// with at least minScore and starts with the same action, best first
+func (c *Catalog) Search(query, goos string, minScore float64) []Match {
+	var matches []Match
+	for _, entry := range c.Entries {
+		commands := entry.commandsFor(goos)
+		if len(commands) == 0 {
+			continue
+		}
+
+		// The task itself counts more than its keywords
+		score := max(fuzzy.Similarity(query, entry.Task),
+			0.9*fuzzy.Coverage(query, entry.Task+" "+strings.Join(entry.Keywords, " ")))
+		// Keywords name other words for the action, e.g. "remove" for delete
+		if score >= minScore && sameAction(query, append([]string{entry.Task}, entry.Keywords...)...) {
+			matches = append(matches, Match{Entry: entry, Commands: commands, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+func (e Entry) commandsFor(goos string) []Command {
+	var commands []Command
+	for _, cmd := range e.Commands {
+		if len(cmd.OS) == 0 && goos != "windows" || slices.Contains(cmd.OS, goos) {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
@@ -0,0 +1,112 @@
+// Package offline answers queries without the API, from previously cached
+// responses and a bundled catalog of common commands.
+package offline
+
+import (
+	"clify/internal/fuzzy"
+	"clify/internal/models"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Command sources shown next to offline results
+const (
+	SourceHistory = "history"
+	SourceCatalog = "catalog"
+)
+
+const (
+	// MinScore is the lowest match score an offline result may have. Queries
+	// that differ in one of three words score 0.67.
+	MinScore = 0.75
+	// MaxCommands bounds the number of commands in an offline answer
+	MaxCommands = 5
+)
+
+// Answer builds a response for query from cached entries and catalog
+// commands for goos. It returns nil when nothing matches.
+func Answer(query, goos string, history []models.CacheEntry, catalog *Catalog) *models.Response {
+	type scored struct {
+		command models.Command
+		score   float64
+	}
+	var results []scored
+	seen := make(map[string]int)
+	add := func(cmd models.Command, score float64) {
+		// A command found twice keeps its first source and its best score
+		if i, ok := seen[cmd.Text]; ok {
+			results[i].score = max(results[i].score, score)
+			return
+		}
+		seen[cmd.Text] = len(results)
+		results = append(results, scored{cmd, score})
+	}
+
+	for _, entry := range history {
+		score := fuzzy.Similarity(query, entry.Query)
+		if score < MinScore || !sameAction(query, entry.Query) {
+			continue
+		}
+
+		var cached models.Response
+		if err := json.Unmarshal([]byte(entry.Response), &cached); err != nil {
+			continue
+		}
+		for _, cmd := range cached.Commands {
+			cmd.Source = SourceHistory
+			cmd.Description = fmt.Sprintf("%s (from \"%s\")", cmd.Description, entry.Query)
+			cmd.MissingTools, cmd.FlagWarnings = nil, nil
+			add(cmd, score)
+		}
+	}
+
+	if catalog != nil {
+		for _, match := range catalog.Search(query, goos, MinScore) {
+			for _, cmd := range match.Commands {
+				add(models.Command{
+					Text:        cmd.Text,
+					Description: cmd.Description,
+					Source:      SourceCatalog,
+				}, match.Score)
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	// History answered this user's own queries, so it wins ties
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	if len(results) > MaxCommands {
+		results = results[:MaxCommands]
+	}
+
+	response := &models.Response{
+		Explanation: "Offline results from past answers and the bundled catalog.",
+		Offline:     true,
+	}
+	for _, r := range results {
+		response.Commands = append(response.Commands, r.command)
+	}
+	return response
+}
+
+// sameAction reports whether query starts with the same word, usually the
+// action, as one of texts, so that the answer to "delete txt files" is
+// never offered for "find txt files"
+func sameAction(query string, texts ...string) bool {
+	words := fuzzy.Tokens(query)
+	if len(words) == 0 {
+		return false
+	}
+	for _, text := range texts {
+		if other := fuzzy.Tokens(text); len(other) > 0 && other[0] == words[0] {
+			return true
+		}
+	}
+	return false
+}
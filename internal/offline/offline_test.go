@@ -0,0 +1,121 @@
+package offline
+
+import (
+	"clify/internal/models"
+	"encoding/json"
+	"testing"
+)
+
+func TestCatalogSearch(t *testing.T) {
+	catalog, err := DefaultCatalog()
+	if err != nil {
+		t.Fatalf("DefaultCatalog() error = %v", err)
+	}
+
+	tests := []struct {
+		query string
+		goos  string
+		want  string
+	}{
+		{"find big files", "linux", "find . -type f -size +100M"},
+		{"replace text in a file", "darwin", "sed -i '' 's/foo/bar/g' file.txt"},
+		{"replace text in a file", "linux", "sed -i 's/foo/bar/g' file.txt"},
+		{"kill a process", "windows", "Stop-Process -Name name"},
+	}
+
+	for _, tt := range tests {
+		matches := catalog.Search(tt.query, tt.goos, MinScore)
+		if len(matches) == 0 {
+			t.Errorf("Search(%q, %q) found nothing", tt.query, tt.goos)
+			continue
+		}
+		var texts []string
+		for _, cmd := range matches[0].Commands {
+			texts = append(texts, cmd.Text)
+		}
+		if !contains(texts, tt.want) {
+			t.Errorf("Search(%q, %q) best match = %q, want it to include %q", tt.query, tt.goos, texts, tt.want)
+		}
+	}
+
+	if matches := catalog.Search("bake a chocolate cake", "linux", MinScore); len(matches) != 0 {
+		t.Errorf("Search() matched an unrelated query: %+v", matches)
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	catalog, err := DefaultCatalog()
+	if err != nil {
+		t.Fatalf("DefaultCatalog() error = %v", err)
+	}
+
+	cached, _ := json.Marshal(models.Response{Commands: []models.Command{{Text: "du -sh .", Description: "Total size"}}})
+	history := []models.CacheEntry{{Query: "show disk usage of this folder", Response: string(cached)}}
+
+	response := Answer("show the disk usage", "linux", history, catalog)
+	if response == nil || !response.Offline {
+		t.Fatalf("Answer() = %+v, want an offline response", response)
+	}
+	if first := response.Commands[0]; first.Text != "du -sh ." {
+		t.Errorf("Commands[0] = %+v, want the cached du command", first)
+	}
+
+	sources := make(map[string]bool)
+	for _, cmd := range response.Commands {
+		sources[cmd.Source] = true
+	}
+	if !sources[SourceHistory] || !sources[SourceCatalog] {
+		t.Errorf("Answer() sources = %v, want history and catalog", sources)
+	}
+	if len(response.Commands) > MaxCommands {
+		t.Errorf("len(Commands) = %d, want at most %d", len(response.Commands), MaxCommands)
+	}
+
+	if response := Answer("bake a chocolate cake", "linux", history, catalog); response != nil {
+		t.Errorf("Answer() for an unrelated query = %+v, want nil", response)
+	}
+}
+
+func TestAnswerNeedsTheSameAction(t *testing.T) {
+	catalog, err := DefaultCatalog()
+	if err != nil {
+		t.Fatalf("DefaultCatalog() error = %v", err)
+	}
+
+	entry := func(query, command string) models.CacheEntry {
+		data, _ := json.Marshal(models.Response{Commands: []models.Command{{Text: command}}})
+		return models.CacheEntry{Query: query, Response: string(data)}
+	}
+	history := []models.CacheEntry{
+		entry("delete all txt files", "rm *.txt"),
+		entry("stop docker containers", "docker stop $(docker ps -q)"),
+	}
+
+	for query, unwanted := range map[string]string{
+		"find all txt files":      "rm *.txt",
+		"start docker containers": "docker stop $(docker ps -q)",
+	} {
+		response := Answer(query, "linux", history, catalog)
+		if response == nil {
+			continue
+		}
+		for _, cmd := range response.Commands {
+			if cmd.Text == unwanted {
+				t.Errorf("Answer(%q) offered %q, the answer to an opposite action", query, unwanted)
+			}
+		}
+	}
+
+	if response := Answer("delete the txt files", "linux", history, catalog); response == nil || response.Commands[0].Text != "rm *.txt" {
+		t.Errorf("Answer() for the same action = %+v, want the cached command", response)
+	}
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package prompt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Templates rendered for command queries and workflow queries
+const (
+	CommandTemplate  = "command.tmpl"
+	WorkflowTemplate = "workflow.tmpl"
+)
+
+// PromptsDir is the directory name searched for template overrides, both
+// below ~/.clify and below a project's .clify directory
+const PromptsDir = "prompts"
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// Data holds the named variables available to templates
+type Data struct {
+	OS          string
+	Arch        string // empty when the target is a remote system
+	Shell       string // target shell, when overridden
+	Distro      string // target distribution, when overridden
+	Remote      bool   // commands run on another system than clify
+	Query       string
+	Environment []string // extra "Name: value" system information lines
+	Unavailable []string // tools known to be missing
+}
+
+// Templates is a set of prompt templates with user overrides applied
+type Templates struct {
+	tmpl    *template.Template
+	sources []string
+}
+
+// Builtin returns the templates shipped in the binary
+func Builtin() *Templates {
+	tmpl := template.Must(template.New("").Funcs(funcs).ParseFS(builtinTemplates, "templates/*.tmpl"))
+	return &Templates{tmpl: tmpl, sources: []string{"builtin"}}
+}
+
+// Load returns the builtin templates overridden by every *.tmpl file in dirs.
+// Later directories take precedence. A file replaces the template of the same
+// name, and {{define "conventions"}} blocks extend the guidelines.
+func Load(dirs []string) (*Templates, error) {
+	t := Builtin()
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		if _, err := t.tmpl.ParseFiles(files...); err != nil {
+			return nil, fmt.Errorf("failed to parse prompt templates in %s: %w", dir, err)
+		}
+		t.sources = append(t.sources, files...)
+	}
+	return t, nil
+}
+
+// SearchDirs returns the override directories: ~/.clify/prompts, then the
+// nearest .clify/prompts found walking up from the working directory
+func SearchDirs() []string {
+	var dirs []string
+	home, err := os.UserHomeDir()
+	if err == nil {
+		dirs = append(dirs, filepath.Join(home, ".clify", PromptsDir))
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return dirs
+	}
+	for {
+		candidate := filepath.Join(dir, ".clify", PromptsDir)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			if len(dirs) == 0 || candidate != dirs[0] {
+				dirs = append(dirs, candidate)
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return dirs
+}
+
+// Sources lists where the templates were loaded from
+func (t *Templates) Sources() []string {
+	return t.sources
+}
+
+// Hash identifies the template set, so that answers to an older or
+// overridden prompt can be told apart. It changes with any template text.
+func (t *Templates) Hash() string {
+	templates := t.tmpl.Templates()
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name() < templates[j].Name()
+	})
+
+	h := sha256.New()
+	for _, tmpl := range templates {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00", tmpl.Name(), tmpl.Tree.Root.String())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Render executes the named template with data
+func (t *Templates) Render(name string, data Data) (string, error) {
+	var b bytes.Buffer
+	if err := t.tmpl.ExecuteTemplate(&b, name, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", name, err)
+	}
+	return b.String(), nil
+}
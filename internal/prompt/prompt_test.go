@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinRender(t *testing.T) {
+	result, err := Builtin().Render(CommandTemplate, Data{
+		OS:          "Linux",
+		Arch:        "amd64",
+		Query:       "find all .txt files",
+		Environment: []string{"Shell: zsh 5.9"},
+		Unavailable: []string{"fd", "ncdu"},
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"- Operating System: Linux\n- Architecture: amd64\n- Shell: zsh 5.9\n",
+		"Query: find all .txt files",
+		"most relevant commands for Linux",
+		"NOT installed on this system: fd, ncdu.",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, result)
+		}
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	userDir := t.TempDir()
+	projectDir := t.TempDir()
+
+	conventions := `{{define "conventions"}}
+- Prefer ripgrep over grep{{end}}`
+	if err := os.WriteFile(filepath.Join(userDir, "house.tmpl"), []byte(conventions), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := Load([]string{userDir, projectDir})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	result, err := templates.Render(CommandTemplate, Data{OS: "Linux", Query: "search"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(result, "- Prefer ripgrep over grep") {
+		t.Errorf("conventions were not applied:\n%s", result)
+	}
+
+	// A project file with the same name replaces the whole prompt
+	if err := os.WriteFile(filepath.Join(projectDir, CommandTemplate), []byte("Project prompt for {{.Query}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templates, err = Load([]string{userDir, projectDir})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	result, _ = templates.Render(CommandTemplate, Data{Query: "search"})
+	if result != "Project prompt for search" {
+		t.Errorf("Render() = %q, want the project override", result)
+	}
+	if len(templates.Sources()) != 3 {
+		t.Errorf("Sources() = %v, want builtin and two files", templates.Sources())
+	}
+}
+
+func TestHash(t *testing.T) {
+	builtin := Builtin().Hash()
+	if builtin != Builtin().Hash() {
+		t.Error("Hash() of the builtin templates is not stable")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "house.tmpl"), []byte(`{{define "conventions"}}- Use fd{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := Load([]string{dir})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if templates.Hash() == builtin {
+		t.Error("Hash() did not change with an override")
+	}
+}
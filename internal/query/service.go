@@ -0,0 +1,413 @@
+package query
+
+import (
+	"clify/internal/client"
+	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/offline"
+	"clify/internal/safety"
+	"clify/internal/snippets"
+	"clify/internal/validate"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+)
+
+const (
+	// SourceFavorite tags commands answered from the favorites library
+	SourceFavorite = "favorite"
+	// SourceTeam tags commands from the team's snippet sources
+	SourceTeam = "team"
+)
+
+// Service answers queries from the cache or the API and annotates the
+// results with local validation. It is shared by the TUI and --json mode.
+type Service struct {
+	client    *client.ClaudeClient
+	cache     *config.CacheManager
+	verifier  *validate.FlagVerifier
+	usage     *config.UsageTracker
+	budget    models.BudgetConfig
+	offline   bool
+	catalog   *offline.Catalog
+	favorites *config.Favorites
+	snippets  *snippets.Index
+	profile   string
+
+	similarityThreshold float64
+}
+
+func NewService(client *client.ClaudeClient, cache *config.CacheManager) *Service {
+	return &Service{
+		client: client,
+		cache:  cache,
+
+		similarityThreshold: config.DefaultSimilarityThreshold,
+	}
+}
+
+// SetSimilarityThreshold sets the score (0-1) at which FindSimilar offers a
+// past query; 0 disables similarity search
+func (s *Service) SetSimilarityThreshold(threshold float64) {
+	s.similarityThreshold = threshold
+}
+
+// FindSimilar returns a cached query similar to query when query itself is
+// not cached, so the caller can offer to reuse its answer
+func (s *Service) FindSimilar(query string) (string, bool) {
+	if _, found := s.cache.Peek(s.client.CacheScope(client.KindCommands), query); found {
+		return "", false
+	}
+	similar, _, found := s.cache.FindSimilar(s.client.CacheScope(client.KindCommands), query, s.similarityThreshold)
+	return similar, found
+}
+
+// Flush saves state written lazily, such as when cached answers were last
+// used. Call it before exiting.
+func (s *Service) Flush() error {
+	return s.cache.Flush()
+}
+
+// SetFavorites makes the favorites library available to FromFavorites
+func (s *Service) SetFavorites(favorites *config.Favorites) {
+	s.favorites = favorites
+}
+
+// Favorites returns the favorites library, or nil if there is none
+func (s *Service) Favorites() *config.Favorites {
+	return s.favorites
+}
+
+// FromFavorites answers query from saved favorites, or returns nil when
+// query is cached or no favorite matches, so that the API is only asked
+// when nothing saved fits. The answer is not annotated yet; see Annotate.
+func (s *Service) FromFavorites(query string) *models.Response {
+	if s.favorites == nil {
+		return nil
+	}
+	if _, found := s.cache.Peek(s.client.CacheScope(client.KindCommands), query); found {
+		return nil
+	}
+
+	matches := s.favorites.Match(query, config.FavoriteMatchScore)
+	if len(matches) == 0 {
+		return nil
+	}
+	return FavoritesResponse(matches)
+}
+
+// FavoritesResponse presents favorites as the commands of a response
+func FavoritesResponse(favorites []models.Favorite) *models.Response {
+	response := &models.Response{Explanation: "From your favorites."}
+	for _, favorite := range favorites {
+		response.Commands = append(response.Commands, models.Command{
+			Text:        favorite.Command,
+			Description: favorite.Description,
+			SafetyLevel: favorite.SafetyLevel,
+			Source:      SourceFavorite,
+		})
+	}
+	return response
+}
+
+// SetSnippets shows matching team snippets above the answer to every query
+func (s *Service) SetSnippets(index *snippets.Index) {
+	s.snippets = index
+}
+
+// EnableFlagVerification turns on checking flags against local documentation
+func (s *Service) EnableFlagVerification() {
+	s.verifier = validate.NewFlagVerifier()
+}
+
+// SetOffline answers every query from the cache and the bundled catalog
+// without calling the API
+func (s *Service) SetOffline(offline bool) {
+	s.offline = offline
+}
+
+// TrackUsage records token usage of API calls and enforces the monthly budget
+func (s *Service) TrackUsage(usage *config.UsageTracker, budget models.BudgetConfig) {
+	s.usage = usage
+	s.budget = budget
+}
+
+// BudgetStatus reports month-to-date spend, the monthly limit and whether it is exceeded
+func (s *Service) BudgetStatus() (spent, limit float64, exceeded bool) {
+	if s.usage == nil {
+		return 0, 0, false
+	}
+	spent = s.usage.MonthToDate(time.Now())
+	return spent, s.budget.Monthly, config.BudgetExceeded(s.budget, spent)
+}
+
+// Query returns commands for query, using the cache when possible, with
+// matching team snippets first
+func (s *Service) Query(ctx context.Context, query string) (*models.Response, error) {
+	response, err := s.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.addTeamSnippets(query, response)
+	return response, nil
+}
+
+func (s *Service) query(ctx context.Context, query string) (*models.Response, error) {
+	// Check cache first
+	if response, found := s.Cached(query); found {
+		s.annotate(response)
+		return response, nil
+	}
+
+	if s.offline {
+		return s.answerOffline(query, nil)
+	}
+
+	if err := s.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	// Query Claude
+	response, err := s.client.QueryCommands(ctx, query)
+	if err != nil {
+		if client.IsUnreachable(err) {
+			return s.answerOffline(query, err)
+		}
+		return nil, err
+	}
+	s.recordUsage(query, response)
+
+	// Cache the response without per-call usage
+	cached := *response
+	cached.Usage = nil
+	if data, err := json.Marshal(cached); err == nil {
+		s.cache.SetScoped(s.client.CacheScope(client.KindCommands), query, string(data))
+	}
+
+	s.annotate(response)
+	return response, nil
+}
+
+// QueryAlternatives re-asks query avoiding the given unavailable tools
+func (s *Service) QueryAlternatives(ctx context.Context, query string, unavailable []string) (*models.Response, error) {
+	if err := s.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	response, err := s.client.QueryAlternatives(ctx, query, unavailable)
+	if err != nil {
+		return nil, err
+	}
+	s.recordUsage(query, response)
+
+	s.annotate(response)
+	return response, nil
+}
+
+// QueryWorkflow returns ordered steps for query, using the cache when possible
+func (s *Service) QueryWorkflow(ctx context.Context, query string) (*models.Workflow, error) {
+	if cached, found := s.cache.GetScoped(s.client.CacheScope(client.KindWorkflow), query); found {
+		var workflow models.Workflow
+		if err := json.Unmarshal([]byte(cached), &workflow); err == nil {
+			workflow.Usage = nil
+			return &workflow, nil
+		}
+	}
+
+	if s.offline {
+		return nil, fmt.Errorf("no cached workflow for %q; workflows need the API", query)
+	}
+
+	if err := s.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	workflow, err := s.client.QueryWorkflow(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if workflow.Usage != nil {
+		s.recordUsage(query, &models.Response{Usage: workflow.Usage})
+	}
+
+	cached := *workflow
+	cached.Usage = nil
+	if data, err := json.Marshal(cached); err == nil {
+		s.cache.SetScoped(s.client.CacheScope(client.KindWorkflow), query, string(data))
+	}
+
+	return workflow, nil
+}
+
+// Cached returns the cached answer to query, or false, without calling the
+// API. The answer is not annotated yet; see Annotate.
+func (s *Service) Cached(query string) (*models.Response, bool) {
+	cached, found := s.cache.GetScoped(s.client.CacheScope(client.KindCommands), query)
+	if !found {
+		return nil, false
+	}
+	var response models.Response
+	if err := json.Unmarshal([]byte(cached), &response); err != nil {
+		return nil, false
+	}
+	response.Usage = nil
+	return &response, true
+}
+
+// Preview is Cached without counting as a use of the entry
+func (s *Service) Preview(query string) (*models.Response, bool) {
+	cached, found := s.cache.Peek(s.client.CacheScope(client.KindCommands), query)
+	if !found {
+		return nil, false
+	}
+	var response models.Response
+	if err := json.Unmarshal([]byte(cached), &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+// TogglePin pins the cached answer to query so that it never expires, or
+// unpins it, and returns the new state
+func (s *Service) TogglePin(query string) (bool, error) {
+	scope := s.client.CacheScope(client.KindCommands)
+	pinned := !s.cache.IsPinned(scope, query)
+	return pinned, s.cache.Pin(scope, query, pinned)
+}
+
+// IsPinned reports whether the cached answer to query is pinned
+func (s *Service) IsPinned(query string) bool {
+	return s.cache.IsPinned(s.client.CacheScope(client.KindCommands), query)
+}
+
+// Target returns the system commands are generated for
+func (s *Service) Target() models.Target {
+	return s.client.Target()
+}
+
+// SetProfile names the configuration profile the service was built from
+func (s *Service) SetProfile(name string) {
+	s.profile = name
+}
+
+// Profile returns the active configuration profile, or "" for none
+func (s *Service) Profile() string {
+	return s.profile
+}
+
+// answerOffline answers query from cached responses and the bundled
+// catalog. cause is the error that made the API unusable, if any.
+func (s *Service) answerOffline(query string, cause error) (*models.Response, error) {
+	if s.catalog == nil {
+		catalog, err := offline.DefaultCatalog()
+		if err != nil {
+			return nil, err
+		}
+		s.catalog = catalog
+	}
+
+	goos := s.goos()
+
+	// Answers from any model or prompt beat none, but never from another system
+	var history []models.CacheEntry
+	for _, entry := range s.cache.Entries() {
+		if entry.Kind == client.KindCommands && entry.OS == goos {
+			history = append(history, entry)
+		}
+	}
+
+	response := offline.Answer(query, goos, history, s.catalog)
+	if response == nil {
+		if cause != nil {
+			return nil, fmt.Errorf("%w (no offline match either)", cause)
+		}
+		return nil, fmt.Errorf("no offline match for %q", query)
+	}
+
+	s.client.Classify(response)
+	s.annotate(response)
+	return response, nil
+}
+
+// addTeamSnippets puts the team snippets matching query above the other
+// commands, replacing those with the same text
+func (s *Service) addTeamSnippets(query string, response *models.Response) {
+	if s.snippets == nil {
+		return
+	}
+	matches := s.snippets.Match(query, s.goos())
+	if len(matches) == 0 {
+		return
+	}
+
+	team := &models.Response{}
+	for _, snippet := range matches {
+		team.Commands = append(team.Commands, models.Command{
+			Text:        snippet.Command,
+			Description: snippet.Description,
+			Source:      SourceTeam,
+		})
+	}
+	// The classifier has the last word only if it is stricter than the team
+	s.client.Classify(team)
+	for i, snippet := range matches {
+		team.Commands[i].SafetyLevel = string(safety.Stricter(models.SafetyLevel(team.Commands[i].SafetyLevel), models.SafetyLevel(snippet.SafetyLevel)))
+	}
+	s.annotate(team)
+
+	commands := team.Commands
+	for _, cmd := range response.Commands {
+		if !slices.ContainsFunc(team.Commands, func(c models.Command) bool { return c.Text == cmd.Text }) {
+			commands = append(commands, cmd)
+		}
+	}
+	response.Commands = commands
+}
+
+// goos returns the operating system commands are generated for
+func (s *Service) goos() string {
+	if goos := s.client.Target().OS; goos != "" {
+		return goos
+	}
+	return runtime.GOOS
+}
+
+// Annotate notes missing tools and unknown flags in a response from Cached
+// or FromFavorites. It runs man and --help, so call it off the UI thread.
+func (s *Service) Annotate(response *models.Response) {
+	s.annotate(response)
+}
+
+func (s *Service) annotate(response *models.Response) {
+	// Local checks say nothing about another system
+	if target := s.client.Target().OS; target != "" && target != runtime.GOOS {
+		return
+	}
+
+	validate.AnnotateMissingTools(response)
+	if s.verifier != nil {
+		s.verifier.AnnotateFlagWarnings(response)
+	}
+}
+
+func (s *Service) checkBudget() error {
+	if s.usage == nil {
+		return nil
+	}
+	return s.usage.CheckBudget(s.budget, time.Now())
+}
+
+func (s *Service) recordUsage(query string, response *models.Response) {
+	if s.usage == nil || response.Usage == nil {
+		return
+	}
+	record := *response.Usage
+	record.Query = query
+	if err := s.usage.Record(record); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+	}
+}
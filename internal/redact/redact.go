@@ -0,0 +1,42 @@
+// Package redact removes secrets such as API keys, tokens and passwords
+// from text before it leaves the machine.
+package redact
+
+import "regexp"
+
+// Placeholder replaces every secret
+const Placeholder = "[REDACTED]"
+
+// A secret never contains whitespace or quotes, so redacting JSON text
+// keeps it valid.
+const secret = `[^\s"'\\]+`
+
+var patterns = []struct {
+	re   *regexp.Regexp
+	repl string // keeps context such as the flag or variable name
+}{
+	// Well-known token formats
+	{regexp.MustCompile(`sk-ant-[A-Za-z0-9_\-]{8,}`), Placeholder},
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{20,}`), Placeholder},
+	{regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{20,}`), Placeholder},
+	{regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{20,}`), Placeholder},
+	{regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`), Placeholder},
+	{regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`), Placeholder},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]+`), Placeholder},
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), Placeholder},
+
+	// Credentials in URLs, headers, flags and assignments
+	{regexp.MustCompile(`(://[^\s:/@"']+:)[^\s"'@/]+@`), "${1}" + Placeholder + "@"},
+	// Header values must contain a digit so that prose like "Basic authentication" stays
+	{regexp.MustCompile(`(\b(?:Bearer|Basic|Token)\s+)[A-Za-z0-9_\-.=+/]*[0-9][A-Za-z0-9_\-.=+/]*`), "${1}" + Placeholder},
+	{regexp.MustCompile(`(?i)(--?(?:password|passwd|pass|token|secret|api-key|apikey|access-key|secret-key)[= ])` + secret), "${1}" + Placeholder},
+	{regexp.MustCompile(`(?i)(\b[A-Z0-9_]*(?:PASSWORD|PASSWD|SECRET|TOKEN|API_KEY|APIKEY|ACCESS_KEY|PRIVATE_KEY)[A-Z0-9_]*\s*[=:]\s*)` + secret), "${1}" + Placeholder},
+}
+
+// String returns s with secrets replaced by Placeholder
+func String(s string) string {
+	for _, p := range patterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
@@ -10,6 +10,7 @@ var (
 	// Dangerous patterns that could cause system damage
 	dangerousPatterns = []string{
 		`rm\s+-rf\s+/`,
+		`rm\s+-rf\s+(~|\$HOME)`,
 		`rm\s+-rf\s+\*`,
 		`rm\s+.*\s+-rf`,
 		`sudo\s+rm`,
@@ -18,9 +19,6 @@ var (
 		`dd\s+if=.*of=/dev/`,
 		`mkfs\s+`,
 		`fdisk\s+`,
-		`format\s+`,
-		`del\s+/s\s+/q`,
-		`rmdir\s+/s\s+/q`,
 		`:\(\)\{.*\}`,        // Fork bomb
 		`curl.*\|\s*sh`,
 		`wget.*\|\s*sh`,
@@ -49,6 +47,61 @@ var (
 		`sudo\s+.*groupdel`,
 	}
 
+	// Dangerous patterns that only apply to one operating system's tools
+	osDangerousPatterns = map[string][]string{
+		"windows": {
+			`format\s+`,
+			`del\s+/s\s+/q`,
+			`rmdir\s+/s\s+/q`,
+			`rd\s+/s\s+/q`,
+			`remove-item\s+.*-recurse.*-force`,
+			`format-volume`,
+			`clear-disk`,
+			`stop-computer`,
+			`restart-computer`,
+			`reg\s+delete`,
+			`bcdedit`,
+			`diskpart`,
+		},
+		"darwin": {
+			`diskutil\s+(erase|zero|secureErase|partitionDisk)`,
+			`csrutil\s+disable`,
+			`nvram\s+`,
+			`srm\s+`,
+		},
+		"linux": {
+			`wipefs\s+`,
+			`shred\s+`,
+			`parted\s+`,
+			`sfdisk\s+`,
+		},
+	}
+
+	// Warning patterns that only apply to one operating system's tools
+	osWarningPatterns = map[string][]string{
+		"windows": {
+			`remove-item\s+`,
+			`move-item\s+`,
+			`copy-item\s+`,
+			`new-item\s+`,
+			`set-itemproperty\s+`,
+			`stop-process\s+`,
+			`winget\s+install`,
+			`choco\s+install`,
+		},
+		"darwin": {
+			`launchctl\s+`,
+			`defaults\s+write`,
+			`port\s+install`,
+		},
+		"linux": {
+			`snap\s+install`,
+			`flatpak\s+install`,
+			`zypper\s+install`,
+			`apk\s+add`,
+		},
+	}
+
 	// Warning patterns that modify system but are generally safe
 	warningPatterns = []string{
 		`sudo\s+`,
@@ -185,18 +238,39 @@ type Classifier struct {
 	safeRegexes      []*regexp.Regexp
 }
 
+// NewClassifier returns a classifier that applies the rules of every
+// operating system, for when the target system is not known
 func NewClassifier() *Classifier {
+	return newClassifier(func(string) bool { return true })
+}
+
+// NewClassifierForOS returns a classifier using the common rules plus the
+// rules for goos (e.g. "linux", "darwin", "windows")
+func NewClassifierForOS(goos string) *Classifier {
+	return newClassifier(func(os string) bool { return os == goos })
+}
+
+func newClassifier(includeOS func(string) bool) *Classifier {
 	c := &Classifier{}
+
+	dangerous := append([]string(nil), dangerousPatterns...)
+	warning := append([]string(nil), warningPatterns...)
+	for _, os := range []string{"windows", "darwin", "linux"} {
+		if includeOS(os) {
+			dangerous = append(dangerous, osDangerousPatterns[os]...)
+			warning = append(warning, osWarningPatterns[os]...)
+		}
+	}
 	
 	// Compile dangerous patterns
-	for _, pattern := range dangerousPatterns {
+	for _, pattern := range dangerous {
 		if regex, err := regexp.Compile("(?i)" + pattern); err == nil {
 			c.dangerousRegexes = append(c.dangerousRegexes, regex)
 		}
 	}
 	
 	// Compile warning patterns
-	for _, pattern := range warningPatterns {
+	for _, pattern := range warning {
 		if regex, err := regexp.Compile("(?i)" + pattern); err == nil {
 			c.warningRegexes = append(c.warningRegexes, regex)
 		}
@@ -265,4 +339,27 @@ func (c *Classifier) GetSafetyMessage(level models.SafetyLevel) string {
 	default:
 		return "Unknown safety level"
 	}
-}
\ No newline at end of file
+}
+
+// Severity orders safety levels from 1 (safe) to 3 (dangerous); unknown
+// levels are 0
+func Severity(level models.SafetyLevel) int {
+	switch level {
+	case models.SafetyLevelSafe:
+		return 1
+	case models.SafetyLevelWarning:
+		return 2
+	case models.SafetyLevelDangerous:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Stricter returns the more severe of two safety levels
+func Stricter(a, b models.SafetyLevel) models.SafetyLevel {
+	if Severity(a) >= Severity(b) {
+		return a
+	}
+	return b
+}
@@ -32,6 +32,7 @@ func TestClassifyCommand(t *testing.T) {
 		// Dangerous commands
 		{"recursive force remove", "rm -rf /", models.SafetyLevelDangerous},
 		{"sudo remove", "sudo rm -rf /var/log", models.SafetyLevelDangerous},
+		{"remove home", "rm -rf ~/", models.SafetyLevelDangerous},
 		{"format disk", "mkfs /dev/sda1", models.SafetyLevelDangerous},
 		{"change to 777", "chmod 777 /etc/passwd", models.SafetyLevelDangerous},
 		{"shutdown system", "shutdown -h now", models.SafetyLevelDangerous},
@@ -49,6 +50,33 @@ func TestClassifyCommand(t *testing.T) {
 	}
 }
 
+func TestClassifyCommandForOS(t *testing.T) {
+	tests := []struct {
+		goos     string
+		command  string
+		expected models.SafetyLevel
+	}{
+		{"windows", "Remove-Item C:\\temp -Recurse -Force", models.SafetyLevelDangerous},
+		{"windows", "del /s /q C:\\temp", models.SafetyLevelDangerous},
+		{"darwin", "diskutil eraseDisk APFS Empty disk2", models.SafetyLevelDangerous},
+		{"linux", "shred -u secrets.txt", models.SafetyLevelDangerous},
+		{"linux", "wipefs -a /dev/sdb", models.SafetyLevelDangerous},
+		// Rules of other systems don't apply
+		{"windows", "format D: /q", models.SafetyLevelDangerous},
+		{"linux", "format D: /q", models.SafetyLevelWarning},
+		{"darwin", "shred -u secrets.txt", models.SafetyLevelWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos+" "+tt.command, func(t *testing.T) {
+			result := NewClassifierForOS(tt.goos).ClassifyCommand(tt.command)
+			if result != tt.expected {
+				t.Errorf("ClassifyCommand(%q) on %s = %v, want %v", tt.command, tt.goos, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGetSafetyIcon(t *testing.T) {
 	classifier := NewClassifier()
 
@@ -87,4 +115,21 @@ func TestGetSafetyMessage(t *testing.T) {
 			t.Errorf("GetSafetyMessage(%v) = %v, want %v", tt.level, result, tt.expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestStricter(t *testing.T) {
+	tests := []struct {
+		a, b, expected models.SafetyLevel
+	}{
+		{models.SafetyLevelSafe, models.SafetyLevelDangerous, models.SafetyLevelDangerous},
+		{models.SafetyLevelWarning, models.SafetyLevelSafe, models.SafetyLevelWarning},
+		{"", models.SafetyLevelSafe, models.SafetyLevelSafe},
+		{"bogus", models.SafetyLevelWarning, models.SafetyLevelWarning},
+	}
+
+	for _, tt := range tests {
+		if result := Stricter(tt.a, tt.b); result != tt.expected {
+			t.Errorf("Stricter(%q, %q) = %q, want %q", tt.a, tt.b, result, tt.expected)
+		}
+	}
+}
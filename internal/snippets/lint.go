@@ -0,0 +1,147 @@
+package snippets
+
+import (
+	"clify/internal/models"
+	"clify/internal/safety"
+	"clify/internal/validate"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// knownOS are the GOOS names a snippet may be limited to
+var knownOS = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "linux", "netbsd", "openbsd", "plan9", "solaris", "windows"}
+
+// Problem is a finding of Lint. Errors make the snippet unusable or its
+// declared safety wrong; warnings are worth a look.
+type Problem struct {
+	File    string
+	Line    int
+	Error   bool
+	Message string
+}
+
+func (p Problem) String() string {
+	level := "warning"
+	if p.Error {
+		level = "error"
+	}
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, level, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.File, level, p.Message)
+}
+
+// Classified is a snippet with the classifier's safety verdict
+type Classified struct {
+	Snippet
+	Verdict models.SafetyLevel
+}
+
+// Report is the result of linting snippet sources
+type Report struct {
+	Snippets []Classified
+	Problems []Problem
+}
+
+// Errors counts the problems that are errors
+func (r *Report) Errors() int {
+	n := 0
+	for _, p := range r.Problems {
+		if p.Error {
+			n++
+		}
+	}
+	return n
+}
+
+// Lint validates the format of every snippet file below dirs and classifies
+// every snippet with the safety classifier
+func Lint(dirs []string) *Report {
+	report := &Report{}
+	classifier := safety.NewClassifier()
+	seen := make(map[string]Snippet) // command -> first definition
+
+	for _, dir := range dirs {
+		files, err := Files(dir)
+		if err != nil {
+			report.Problems = append(report.Problems, Problem{File: dir, Error: true, Message: err.Error()})
+			continue
+		}
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				report.Problems = append(report.Problems, Problem{File: path, Error: true, Message: err.Error()})
+				continue
+			}
+			snippets, err := parseFile(path)
+			if err != nil {
+				report.Problems = append(report.Problems, Problem{File: path, Error: true, Message: strings.TrimPrefix(err.Error(), path+": ")})
+				continue
+			}
+			if len(snippets) == 0 && len(strings.TrimSpace(string(data))) > 0 {
+				report.Problems = append(report.Problems, Problem{File: path, Message: "no snippets; expected a top-level snippets: list"})
+			}
+
+			for _, snippet := range snippets {
+				report.Problems = append(report.Problems, check(snippet, seen)...)
+				if _, dup := seen[snippet.Command]; !dup {
+					seen[snippet.Command] = snippet
+				}
+				if snippet.Command != "" {
+					report.Snippets = append(report.Snippets, Classified{snippet, classifier.ClassifyCommand(snippet.Command)})
+				}
+			}
+		}
+	}
+
+	for _, c := range report.Snippets {
+		if c.SafetyLevel != "" && safety.Severity(models.SafetyLevel(c.SafetyLevel)) < safety.Severity(c.Verdict) {
+			report.Problems = append(report.Problems, Problem{File: c.File, Line: c.Line, Error: true,
+				Message: fmt.Sprintf("declared %s but classified %s: %s", c.SafetyLevel, c.Verdict, c.Command)})
+		}
+	}
+	return report
+}
+
+// check validates the fields of one snippet
+func check(snippet Snippet, seen map[string]Snippet) []Problem {
+	var problems []Problem
+	add := func(isError bool, format string, args ...any) {
+		problems = append(problems, Problem{File: snippet.File, Line: snippet.Line, Error: isError, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(snippet.Command) == "" {
+		add(true, "missing command")
+		return problems
+	}
+	if strings.TrimSpace(snippet.Query) == "" {
+		add(true, "missing query for %s", snippet.Command)
+	}
+	if snippet.Description == "" {
+		add(false, "no description for %s", snippet.Command)
+	}
+	switch models.SafetyLevel(snippet.SafetyLevel) {
+	case "", models.SafetyLevelSafe, models.SafetyLevelWarning, models.SafetyLevelDangerous:
+	default:
+		add(true, "safety_level %q is not safe, warning or dangerous", snippet.SafetyLevel)
+	}
+	for _, goos := range snippet.OS {
+		if !slices.Contains(knownOS, goos) {
+			add(true, "unknown os %q; use GOOS names such as linux, darwin or windows", goos)
+		}
+	}
+
+	// PowerShell is not parsed; everything else must be valid shell
+	if !slices.Equal(snippet.OS, []string{"windows"}) {
+		if err := validate.SyntaxError(snippet.Command, ""); err != nil {
+			add(true, "syntax error in %s: %v", snippet.Command, err)
+		}
+	}
+
+	if first, dup := seen[snippet.Command]; dup {
+		add(false, "duplicate of %s:%d: %s", first.File, first.Line, snippet.Command)
+	}
+	return problems
+}
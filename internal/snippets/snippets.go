@@ -0,0 +1,188 @@
+// Package snippets indexes commands a team shares as YAML files in local
+// directories, such as a checkout of a team repository.
+package snippets
+
+import (
+	"bytes"
+	"clify/internal/config"
+	"clify/internal/fuzzy"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MinScore is the score (0-1) at which a snippet answers a query
+	MinScore = 0.7
+	// MaxMatches bounds the snippets shown for one query
+	MaxMatches = 3
+)
+
+// File is the format of a snippet file:
+//
+//	snippets:
+//	  - query: show disk usage
+//	    command: du -sh *
+//	    description: Size of each entry in the current directory
+//	    tags: [disk]
+//	    os: [linux, darwin]   # optional; every system when empty
+//	    safety_level: safe    # optional; the team's verdict
+type File struct {
+	Snippets []Snippet `yaml:"snippets"`
+}
+
+// Snippet is a vetted command for a task. The fields match favorites so
+// that a favorite can be shared by copying it into a snippet file.
+type Snippet struct {
+	Query       string   `yaml:"query"`
+	Command     string   `yaml:"command"`
+	Description string   `yaml:"description,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	OS          []string `yaml:"os,omitempty"`
+	SafetyLevel string   `yaml:"safety_level,omitempty"`
+
+	File string `yaml:"-"` // where the snippet was defined
+	Line int    `yaml:"-"`
+}
+
+// Index holds the snippets of every configured source
+type Index struct {
+	Snippets []Snippet
+}
+
+// Load indexes the snippet files below dirs. Files that fail to parse are
+// skipped and reported in the returned errors; run lint for details.
+func Load(dirs []string) (*Index, []error) {
+	index := &Index{}
+	var errs []error
+	for _, dir := range dirs {
+		files, err := Files(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, path := range files {
+			snippets, err := parseFile(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			index.Snippets = append(index.Snippets, snippets...)
+		}
+	}
+	return index, errs
+}
+
+// Files returns the .yaml and .yml files below dir, skipping hidden
+// directories such as .git
+func Files(dir string) ([]string, error) {
+	dir = config.ExpandHome(dir)
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snippet source %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// Match returns up to MaxMatches snippets for goos whose query or
+// description matches query, best first
+func (idx *Index) Match(query, goos string) []Snippet {
+	type scored struct {
+		snippet Snippet
+		score   float64
+	}
+	var matches []scored
+	for _, snippet := range idx.Snippets {
+		if len(snippet.OS) > 0 && !slices.Contains(snippet.OS, goos) {
+			continue
+		}
+		text := strings.Join(append([]string{snippet.Query, snippet.Description}, snippet.Tags...), " ")
+		score := max(fuzzy.Similarity(query, snippet.Query), 0.9*fuzzy.Coverage(query, text))
+		if score >= MinScore {
+			matches = append(matches, scored{snippet, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	var result []Snippet
+	for _, m := range matches {
+		if len(result) == MaxMatches {
+			break
+		}
+		result = append(result, m.snippet)
+	}
+	return result
+}
+
+// parseFile reads the snippets of one file, recording where each is defined
+func parseFile(path string) ([]Snippet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, lines, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range file.Snippets {
+		file.Snippets[i].File = path
+		file.Snippets[i].Line = lines[i]
+	}
+	return file.Snippets, nil
+}
+
+// decode parses a snippet file strictly, rejecting unknown fields, and
+// returns the line of each snippet
+func decode(data []byte) (File, []int, error) {
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return File{}, nil, nil // an empty file
+		}
+		return File{}, nil, err
+	}
+
+	lines := make([]int, len(file.Snippets))
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err == nil && len(root.Content) > 0 {
+		mapping := root.Content[0]
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value != "snippets" {
+				continue
+			}
+			for j, item := range mapping.Content[i+1].Content {
+				if j < len(lines) {
+					lines[j] = item.Line
+				}
+			}
+		}
+	}
+	return file, lines, nil
+}
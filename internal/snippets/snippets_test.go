@@ -0,0 +1,98 @@
+package snippets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const teamFile = `snippets:
+  - query: show disk usage
+    command: du -sh *
+    description: Size of each entry in the current directory
+    tags: [disk]
+  - query: restart the web server
+    command: sudo systemctl restart nginx
+    description: Restart nginx
+    os: [linux]
+    safety_level: warning
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAndMatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ops", "team.yaml"), teamFile)
+	writeFile(t, filepath.Join(dir, ".git", "config.yml"), "not: snippets")
+	writeFile(t, filepath.Join(dir, "broken.yaml"), "snippets: [")
+
+	index, errs := Load([]string{dir})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.yaml") {
+		t.Errorf("Load() errors = %v, want one for broken.yaml", errs)
+	}
+	if len(index.Snippets) != 2 {
+		t.Fatalf("Load() indexed %d snippets, want 2", len(index.Snippets))
+	}
+	if s := index.Snippets[1]; s.Line != 6 || !strings.HasSuffix(s.File, "team.yaml") {
+		t.Errorf("snippet location = %s:%d, want team.yaml:6", s.File, s.Line)
+	}
+
+	if got := index.Match("disk usage", "linux"); len(got) != 1 || got[0].Command != "du -sh *" {
+		t.Errorf("Match(disk usage) = %+v", got)
+	}
+	if got := index.Match("restart web server", "darwin"); len(got) != 0 {
+		t.Errorf("Match() returned a linux-only snippet for darwin: %+v", got)
+	}
+	if got := index.Match("kill a process", "linux"); len(got) != 0 {
+		t.Errorf("Match(kill a process) = %+v, want none", got)
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "team.yaml"), teamFile)
+	writeFile(t, filepath.Join(dir, "bad.yaml"), `snippets:
+  - query: clean up
+    command: rm -rf /tmp/build
+    safety_level: safe
+  - command: echo "unterminated
+    os: [macos]
+  - query: show disk usage again
+    command: du -sh *
+    description: Same as the team one
+`)
+	writeFile(t, filepath.Join(dir, "typo.yaml"), "snippets:\n  - query: q\n    comand: ls\n")
+
+	report := Lint([]string{dir})
+	var messages []string
+	for _, p := range report.Problems {
+		messages = append(messages, p.String())
+	}
+	all := strings.Join(messages, "\n")
+
+	for _, want := range []string{
+		"bad.yaml:2: error: declared safe but classified dangerous",
+		"bad.yaml:5: error: missing query",
+		"bad.yaml:5: error: unknown os \"macos\"",
+		"bad.yaml:5: error: syntax error",
+		"bad.yaml:2: warning: no description",
+		"warning: duplicate of",
+		"typo.yaml: error:",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("Lint() problems lack %q:\n%s", want, all)
+		}
+	}
+	if len(report.Snippets) != 5 {
+		t.Errorf("Lint() classified %d snippets, want 5", len(report.Snippets))
+	}
+}
@@ -0,0 +1,119 @@
+package transfer
+
+import (
+	"clify/internal/client"
+	"clify/internal/models"
+	"clify/internal/safety"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var cacheCodec = codec[models.CacheEntry]{
+	header: []string{"timestamp", "last_used", "pinned", "kind", "model", "os", "shell", "distro", "prompt", "schema", "query", "response"},
+	toRow: func(e models.CacheEntry) []string {
+		return []string{formatTime(e.Timestamp), formatTime(e.LastUsed), strconv.FormatBool(e.Pinned),
+			e.Kind, e.Model, e.OS, e.Shell, e.Distro, e.Prompt, e.Schema, e.Query, e.Response}
+	},
+	fromRow: func(get func(string) string) (models.CacheEntry, error) {
+		entry := models.CacheEntry{
+			CacheScope: models.CacheScope{Kind: get("kind"), Model: get("model"), OS: get("os"), Shell: get("shell"),
+				Distro: get("distro"), Prompt: get("prompt"), Schema: get("schema")},
+			Query:    get("query"),
+			Response: get("response"),
+			Pinned:   get("pinned") == "true",
+		}
+		var err error
+		if entry.Timestamp, err = parseTime(get("timestamp")); err != nil {
+			return entry, err
+		}
+		if entry.LastUsed, err = parseTime(get("last_used")); err != nil {
+			return entry, err
+		}
+		return entry, nil
+	},
+}
+
+// WriteCache writes cache entries in format
+func WriteCache(w io.Writer, entries []models.CacheEntry, format Format) error {
+	return write(w, entries, format, cacheCodec)
+}
+
+// ReadCache reads cache entries written by WriteCache, or a cache.json file
+func ReadCache(r io.Reader, format Format) ([]models.CacheEntry, error) {
+	entries, err := read(r, format, "entries", cacheCodec)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Query == "" || entry.Response == "" {
+			return nil, fmt.Errorf("cache entry without query or response")
+		}
+	}
+	return entries, nil
+}
+
+// Cache returns the entries in the date range, without commands above the
+// safety limit and with secrets redacted. Every command is classified again
+// and keeps the stricter of its stored and computed levels, so that a shared
+// file cannot mark a dangerous command safe. A workflow is left out whole if
+// any step is above the limit, as are responses left without commands or
+// that cannot be parsed.
+func (o Options) Cache(entries []models.CacheEntry) []models.CacheEntry {
+	classifiers := map[string]*safety.Classifier{}
+	var selected []models.CacheEntry
+	for _, entry := range entries {
+		if !o.inRange(entry.Timestamp) {
+			continue
+		}
+		classifier, ok := classifiers[entry.OS]
+		if !ok {
+			classifier = classifierFor(entry.OS)
+			classifiers[entry.OS] = classifier
+		}
+		response, ok := o.cacheResponse(entry, classifier)
+		if !ok {
+			continue
+		}
+		entry.Query = o.clean(entry.Query)
+		entry.Response = o.clean(response)
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+func (o Options) cacheResponse(entry models.CacheEntry, classifier *safety.Classifier) (string, bool) {
+	if entry.Kind == client.KindWorkflow {
+		var workflow models.Workflow
+		if err := json.Unmarshal([]byte(entry.Response), &workflow); err != nil {
+			return "", false
+		}
+		for i, step := range workflow.Steps {
+			workflow.Steps[i].SafetyLevel = reclassify(classifier, step.Text, step.SafetyLevel)
+			if !o.allows(workflow.Steps[i].SafetyLevel) {
+				return "", false
+			}
+		}
+		data, err := json.Marshal(workflow)
+		return string(data), err == nil
+	}
+
+	var response models.Response
+	if err := json.Unmarshal([]byte(entry.Response), &response); err != nil {
+		return "", false
+	}
+	var commands []models.Command
+	for _, cmd := range response.Commands {
+		cmd.SafetyLevel = reclassify(classifier, cmd.Text, cmd.SafetyLevel)
+		if o.allows(cmd.SafetyLevel) {
+			commands = append(commands, cmd)
+		}
+	}
+	if len(commands) == 0 {
+		return "", false
+	}
+	response.Commands = commands
+	data, err := json.Marshal(response)
+	return string(data), err == nil
+}
@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/safety"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var favoritesCodec = codec[models.Favorite]{
+	header: []string{"added", "command", "query", "description", "tags", "safety_level"},
+	toRow: func(f models.Favorite) []string {
+		return []string{formatTime(f.Added), f.Command, f.Query, f.Description, strings.Join(f.Tags, ","), f.SafetyLevel}
+	},
+	fromRow: func(get func(string) string) (models.Favorite, error) {
+		favorite := models.Favorite{
+			Command:     get("command"),
+			Query:       get("query"),
+			Description: get("description"),
+			Tags:        config.ParseTags(get("tags")),
+			SafetyLevel: get("safety_level"),
+		}
+		var err error
+		favorite.Added, err = parseTime(get("added"))
+		return favorite, err
+	},
+}
+
+// WriteFavorites writes favorites in format
+func WriteFavorites(w io.Writer, favorites []models.Favorite, format Format) error {
+	return write(w, favorites, format, favoritesCodec)
+}
+
+// ReadFavorites reads favorites written by WriteFavorites
+func ReadFavorites(r io.Reader, format Format) ([]models.Favorite, error) {
+	favorites, err := read(r, format, "favorites", favoritesCodec)
+	if err != nil {
+		return nil, err
+	}
+	for _, favorite := range favorites {
+		if favorite.Command == "" {
+			return nil, fmt.Errorf("favorite without command")
+		}
+	}
+	return favorites, nil
+}
+
+// Favorites returns the favorites added in the date range and within the
+// safety limit, with secrets redacted. Each command is classified again and
+// keeps the stricter of its stored and computed levels.
+func (o Options) Favorites(favorites []models.Favorite) []models.Favorite {
+	classifier := safety.NewClassifier()
+	var selected []models.Favorite
+	for _, favorite := range favorites {
+		favorite.SafetyLevel = reclassify(classifier, favorite.Command, favorite.SafetyLevel)
+		if !o.inRange(favorite.Added) || !o.allows(favorite.SafetyLevel) {
+			continue
+		}
+		favorite.Command = o.clean(favorite.Command)
+		favorite.Query = o.clean(favorite.Query)
+		favorite.Description = o.clean(favorite.Description)
+		selected = append(selected, favorite)
+	}
+	return selected
+}
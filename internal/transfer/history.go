@@ -0,0 +1,59 @@
+package transfer
+
+import (
+	"clify/internal/models"
+	"clify/internal/safety"
+	"fmt"
+	"io"
+)
+
+var historyCodec = codec[models.HistoryEntry]{
+	header: []string{"timestamp", "id", "query", "cwd", "command"},
+	toRow: func(e models.HistoryEntry) []string {
+		return []string{formatTime(e.Timestamp), e.ID, e.Query, e.Cwd, e.Command}
+	},
+	fromRow: func(get func(string) string) (models.HistoryEntry, error) {
+		entry := models.HistoryEntry{ID: get("id"), Query: get("query"), Cwd: get("cwd"), Command: get("command")}
+		var err error
+		entry.Timestamp, err = parseTime(get("timestamp"))
+		return entry, err
+	},
+}
+
+// WriteHistory writes history entries in format
+func WriteHistory(w io.Writer, entries []models.HistoryEntry, format Format) error {
+	return write(w, entries, format, historyCodec)
+}
+
+// ReadHistory reads history entries written by WriteHistory
+func ReadHistory(r io.Reader, format Format) ([]models.HistoryEntry, error) {
+	entries, err := read(r, format, "history", historyCodec)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Query == "" {
+			return nil, fmt.Errorf("history entry without query")
+		}
+	}
+	return entries, nil
+}
+
+// History returns the entries in the date range whose chosen command is
+// within the safety limit, with secrets redacted
+func (o Options) History(entries []models.HistoryEntry) []models.HistoryEntry {
+	classifier := safety.NewClassifier()
+	var selected []models.HistoryEntry
+	for _, entry := range entries {
+		if !o.inRange(entry.Timestamp) {
+			continue
+		}
+		if entry.Command != "" && !o.allows(string(classifier.ClassifyCommand(entry.Command))) {
+			continue
+		}
+		entry.Query = o.clean(entry.Query)
+		entry.Command = o.clean(entry.Command)
+		selected = append(selected, entry)
+	}
+	return selected
+}
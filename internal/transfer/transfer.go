@@ -0,0 +1,239 @@
+// Package transfer exports and imports the cache, history and favorites as
+// JSON or CSV, so that clify state can move between machines.
+package transfer
+
+import (
+	"bytes"
+	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/redact"
+	"clify/internal/safety"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is a file format for exports and imports
+type Format string
+
+const (
+	JSON Format = "json"
+	CSV  Format = "csv"
+)
+
+// DetectFormat returns the named format, or CSV for a .csv path and JSON
+// otherwise
+func DetectFormat(name, path string) (Format, error) {
+	if name == "" {
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			return CSV, nil
+		}
+		return JSON, nil
+	}
+	switch Format(name) {
+	case JSON, "":
+		return JSON, nil
+	case CSV:
+		return CSV, nil
+	default:
+		return "", fmt.Errorf("unknown format %q; use json or csv", name)
+	}
+}
+
+// Options selects and cleans what is exported or imported
+type Options struct {
+	Since     time.Time          // zero means no lower bound
+	Until     time.Time          // zero means no upper bound
+	MaxSafety models.SafetyLevel // commands more dangerous are left out; empty allows all
+	Redact    bool               // replace secrets with a placeholder
+}
+
+// ParseTime reads a date ("2025-01-31"), an RFC 3339 time, or a duration
+// before now such as "7d" or "12h"
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if d, err := config.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q; use YYYY-MM-DD, RFC 3339 or a duration such as 7d", s)
+}
+
+// ParseUntil is ParseTime for an upper bound: a date means the end of that
+// day, so that --until 2025-01-31 includes entries from the 31st
+func ParseUntil(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return ParseTime(s, now)
+}
+
+// ParseSafety accepts safe, warning, dangerous or empty
+func ParseSafety(s string) (models.SafetyLevel, error) {
+	level := models.SafetyLevel(s)
+	if s != "" && safety.Severity(level) == 0 {
+		return "", fmt.Errorf("unknown safety level %q; use safe, warning or dangerous", s)
+	}
+	return level, nil
+}
+
+func (o Options) inRange(t time.Time) bool {
+	return (o.Since.IsZero() || !t.Before(o.Since)) && (o.Until.IsZero() || !t.After(o.Until))
+}
+
+// allows reports whether level is within the safety limit; with a limit,
+// unclassified commands are left out
+func (o Options) allows(level string) bool {
+	if o.MaxSafety == "" {
+		return true
+	}
+	severity := safety.Severity(models.SafetyLevel(level))
+	return severity > 0 && severity <= safety.Severity(o.MaxSafety)
+}
+
+// classifierFor returns the classifier for commands meant for goos, or for
+// any system if goos is not known
+func classifierFor(goos string) *safety.Classifier {
+	if goos == "" {
+		return safety.NewClassifier()
+	}
+	return safety.NewClassifierForOS(goos)
+}
+
+// reclassify returns the stricter of the level stored with command and the
+// level the classifier gives it
+func reclassify(classifier *safety.Classifier, command, level string) string {
+	return string(safety.Stricter(models.SafetyLevel(level), classifier.ClassifyCommand(command)))
+}
+
+func (o Options) clean(s string) string {
+	if o.Redact {
+		return redact.String(s)
+	}
+	return s
+}
+
+// codec converts one kind of entry to and from CSV rows
+type codec[T any] struct {
+	header []string
+	toRow  func(T) []string
+	// fromRow reads an entry; get returns the named column, or "" if absent
+	fromRow func(get func(string) string) (T, error)
+}
+
+func write[T any](w io.Writer, items []T, format Format, c codec[T]) error {
+	if format == CSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(c.header); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := cw.Write(c.toRow(item)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// read parses items written by write. JSON may also be an object with the
+// items under key, such as cache.json itself.
+func read[T any](r io.Reader, format Format, key string, c codec[T]) ([]T, error) {
+	if format == CSV {
+		return readCSV(r, c)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var items []T
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapped map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if wrapped[key] == nil {
+			return nil, fmt.Errorf("invalid JSON: expected a list or an object with %q", key)
+		}
+		data = wrapped[key]
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return items, nil
+}
+
+func readCSV[T any](r io.Reader, c codec[T]) ([]T, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns[c.header[0]]; !ok {
+		return nil, fmt.Errorf("invalid CSV: missing %s column; expected %s", c.header[0], strings.Join(c.header, ","))
+	}
+
+	var items []T
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		item, err := c.fromRow(get)
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %w", line, err)
+		}
+		items = append(items, item)
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,168 @@
+package transfer
+
+import (
+	"bytes"
+	"clify/internal/models"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cacheEntry(t *testing.T, query string, ts time.Time, commands ...models.Command) models.CacheEntry {
+	t.Helper()
+	data, err := json.Marshal(models.Response{Explanation: query, Commands: commands})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.CacheEntry{
+		CacheScope: models.CacheScope{Model: "m", OS: "linux", Prompt: "p", Schema: "s"},
+		Query:      query,
+		Response:   string(data),
+		Timestamp:  ts,
+	}
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []models.CacheEntry{
+		cacheEntry(t, "list files", ts, models.Command{Text: "ls -la", SafetyLevel: "safe"}),
+		cacheEntry(t, "show \"quoted\", text", ts.Add(time.Hour), models.Command{Text: "echo 'a,b'\nls", SafetyLevel: "safe"}),
+	}
+	entries[1].Pinned = true
+
+	for _, format := range []Format{JSON, CSV} {
+		var b bytes.Buffer
+		if err := WriteCache(&b, entries, format); err != nil {
+			t.Fatalf("WriteCache(%s) error = %v", format, err)
+		}
+		got, err := ReadCache(&b, format)
+		if err != nil {
+			t.Fatalf("ReadCache(%s) error = %v", format, err)
+		}
+		for i := range got {
+			got[i].Timestamp = got[i].Timestamp.UTC()
+		}
+		if !reflect.DeepEqual(got, entries) {
+			t.Errorf("ReadCache(%s) = %+v, want %+v", format, got, entries)
+		}
+	}
+
+	// cache.json itself can be imported
+	data, _ := json.Marshal(map[string]any{"version": 2, "entries": entries})
+	if got, err := ReadCache(bytes.NewReader(data), JSON); err != nil || len(got) != 2 {
+		t.Errorf("ReadCache(cache.json) = %d entries, %v", len(got), err)
+	}
+}
+
+func TestOptionsFilterAndRedact(t *testing.T) {
+	now := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	entries := []models.CacheEntry{
+		cacheEntry(t, "old", now.AddDate(0, 0, -30), models.Command{Text: "ls", SafetyLevel: "safe"}),
+		cacheEntry(t, "clean up", now.AddDate(0, 0, -1),
+			models.Command{Text: "rm -rf build", SafetyLevel: "dangerous"},
+			models.Command{Text: "make clean", SafetyLevel: "warning"}),
+		cacheEntry(t, "wipe disk", now.AddDate(0, 0, -1), models.Command{Text: "dd if=/dev/zero of=/dev/sda", SafetyLevel: "dangerous"}),
+		cacheEntry(t, "login with TOKEN=abc123def", now, models.Command{Text: "curl -H 'Authorization: Bearer abc123def' https://api", SafetyLevel: "safe"}),
+	}
+
+	since, err := ParseTime("7d", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := Options{Since: since, MaxSafety: models.SafetyLevelWarning, Redact: true}
+	got := options.Cache(entries)
+
+	var queries []string
+	for _, entry := range got {
+		queries = append(queries, entry.Query)
+	}
+	if want := []string{"clean up", "login with TOKEN=[REDACTED]"}; !reflect.DeepEqual(queries, want) {
+		t.Fatalf("Cache() queries = %q, want %q", queries, want)
+	}
+	if strings.Contains(got[0].Response, "rm -rf") || !strings.Contains(got[0].Response, "make clean") {
+		t.Errorf("Cache() kept a command above the limit: %s", got[0].Response)
+	}
+	if strings.Contains(got[1].Response, "abc123def") {
+		t.Errorf("Cache() did not redact the response: %s", got[1].Response)
+	}
+}
+
+func TestImportReclassifiesCommands(t *testing.T) {
+	now := time.Now()
+	entries := []models.CacheEntry{
+		cacheEntry(t, "free space", now, models.Command{Text: "rm -rf ~", SafetyLevel: "safe"}),
+		cacheEntry(t, "list files", now, models.Command{Text: "ls -la", SafetyLevel: "safe"}),
+	}
+	workflow, _ := json.Marshal(models.Workflow{Steps: []models.WorkflowStep{{ID: 1, Text: "rm -rf /", SafetyLevel: "safe"}}})
+	entries = append(entries, models.CacheEntry{CacheScope: models.CacheScope{Kind: "workflow"}, Query: "reset", Response: string(workflow), Timestamp: now})
+
+	got := Options{}.Cache(entries)
+	if len(got) != 3 || !strings.Contains(got[0].Response, `"safety_level":"dangerous"`) || !strings.Contains(got[2].Response, `"safety_level":"dangerous"`) {
+		t.Errorf("Cache() kept the declared levels: %+v", got)
+	}
+	if got := (Options{MaxSafety: models.SafetyLevelWarning}).Cache(entries); len(got) != 1 || got[0].Query != "list files" {
+		t.Errorf("Cache() with --max-safety warning = %+v, want only list files", got)
+	}
+
+	favorites := Options{}.Favorites([]models.Favorite{{Command: "rm -rf ~", SafetyLevel: "safe"}})
+	if favorites[0].SafetyLevel != string(models.SafetyLevelDangerous) {
+		t.Errorf("Favorites() level = %q, want dangerous", favorites[0].SafetyLevel)
+	}
+}
+
+func TestParseUntilIncludesTheDay(t *testing.T) {
+	until, err := ParseUntil("2025-01-31", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := Options{Until: until}
+	if evening := time.Date(2025, 1, 31, 23, 0, 0, 0, time.Local); !options.inRange(evening) {
+		t.Errorf("--until 2025-01-31 excludes %v", evening)
+	}
+	if next := time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local); options.inRange(next) {
+		t.Errorf("--until 2025-01-31 includes %v", next)
+	}
+}
+
+func TestHistoryAndFavoritesCSV(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	history := []models.HistoryEntry{{ID: "a1", Timestamp: ts, Query: "list files", Cwd: "/tmp", Command: "ls -la"}}
+	var b bytes.Buffer
+	WriteHistory(&b, history, CSV)
+	if got, err := ReadHistory(&b, CSV); err != nil || !reflect.DeepEqual(got, history) {
+		t.Errorf("ReadHistory() = %+v, %v", got, err)
+	}
+
+	favorites := []models.Favorite{{Command: "du -sh *", Query: "disk usage", Tags: []string{"disk", "daily"}, SafetyLevel: "safe", Added: ts}}
+	b.Reset()
+	WriteFavorites(&b, favorites, CSV)
+	if got, err := ReadFavorites(&b, CSV); err != nil || !reflect.DeepEqual(got, favorites) {
+		t.Errorf("ReadFavorites() = %+v, %v", got, err)
+	}
+
+	if _, err := ReadFavorites(strings.NewReader("query,tags\nq,t\n"), CSV); err == nil {
+		t.Error("ReadFavorites() accepted a CSV without a command column")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name, path string
+		want       Format
+	}{
+		{"", "cache.csv", CSV},
+		{"", "cache.json", JSON},
+		{"", "", JSON},
+		{"csv", "export.txt", CSV},
+	}
+	for _, tt := range tests {
+		if got, err := DetectFormat(tt.name, tt.path); err != nil || got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %q, %v, want %q", tt.name, tt.path, got, err, tt.want)
+		}
+	}
+	if _, err := DetectFormat("xml", ""); err == nil {
+		t.Error("DetectFormat(xml) succeeded")
+	}
+}
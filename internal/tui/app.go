@@ -1,12 +1,13 @@
 package tui
 
 import (
-	"clify/internal/client"
 	"clify/internal/config"
+	"clify/internal/envinfo"
 	"clify/internal/models"
+	"clify/internal/query"
 	"clify/internal/safety"
+	"clify/internal/validate"
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -18,25 +19,39 @@ import (
 
 type Model struct {
 	state      *models.AppState
-	client     *client.ClaudeClient
-	cache      *config.CacheManager
+	service    *query.Service
+	history    *config.History
 	classifier *safety.Classifier
 	textInput  textinput.Model
 	viewport   struct {
 		width  int
 		height int
 	}
-	lastError    string
-	showingModal bool
-	modalMessage string
-	spinner      *Spinner
-	loading      bool
-	historyIndex int
+	lastError     string
+	showingModal  bool
+	modalMessage  string
+	spinner       *Spinner
+	loading       bool
+	historyIndex  int
+	packageMgr    string
+	workflowMode  bool
+	workflow      *models.Workflow
+	stepStatus    []stepStatus
+	selectedStep  int
+	confirmStep   bool
+	similarQuery  string            // past query offered instead of state.Query
+	historyID     string            // history entry of the current query
+	search        *historySearch    // Ctrl+R overlay, when open
+	browser       *favoritesBrowser // Ctrl+F overlay, when open
+	tagInput      *textinput.Model  // tags for a favorite being saved
+	fromFavorites bool              // the response came from favorites, not the API
 }
 
 type msgResponse struct {
-	response *models.Response
-	err      error
+	response      *models.Response
+	err           error
+	fromFavorites bool
+	command       string // selected if the response has it, e.g. the one chosen last time
 }
 
 type msgError struct {
@@ -49,7 +64,7 @@ type msgQuitWithMessage struct {
 	message string
 }
 
-func NewModel(client *client.ClaudeClient, cache *config.CacheManager) *Model {
+func NewModel(service *query.Service, history *config.History) *Model {
 	ti := textinput.New()
 	ti.Placeholder = "Enter your query..."
 	ti.Focus()
@@ -58,9 +73,8 @@ func NewModel(client *client.ClaudeClient, cache *config.CacheManager) *Model {
 	ti.ShowSuggestions = true
 
 	// Set up search history for autocomplete
-	searchHistory := cache.GetSearchHistory()
-	if len(searchHistory) > 0 {
-		ti.SetSuggestions(searchHistory)
+	if queries := history.Queries(); len(queries) > 0 {
+		ti.SetSuggestions(queries)
 	}
 
 	return &Model{
@@ -68,13 +82,14 @@ func NewModel(client *client.ClaudeClient, cache *config.CacheManager) *Model {
 			Mode:            "input",
 			SelectedCommand: 0,
 		},
-		client:       client,
-		cache:        cache,
+		service:      service,
+		history:      history,
 		classifier:   safety.NewClassifier(),
 		textInput:    ti,
 		spinner:      NewSpinner(),
 		loading:      false,
 		historyIndex: -1,
+		packageMgr:   envinfo.DetectPackageManager(),
 	}
 }
 
@@ -106,20 +121,40 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.lastError = msg.err.Error()
 			return m, nil
 		}
+		if msg.fromFavorites {
+			m.showFavorites(msg.response)
+			return m, nil
+		}
 		m.state.Response = msg.response
 		m.state.Mode = "selection"
 		m.state.SelectedCommand = 0
+		for i, cmd := range msg.response.Commands {
+			if cmd.Text == msg.command {
+				m.state.SelectedCommand = i
+			}
+		}
+		m.fromFavorites = false
 		m.textInput.Blur()
 		m.lastError = ""
 
 		// Refresh autocomplete suggestions with updated search history
-		searchHistory := m.cache.GetSearchHistory()
-		if len(searchHistory) > 0 {
-			m.textInput.SetSuggestions(searchHistory)
+		if queries := m.history.Queries(); len(queries) > 0 {
+			m.textInput.SetSuggestions(queries)
 		}
 
 		return m, nil
 
+	case msgWorkflow:
+		return m.handleWorkflowMsg(msg)
+
+	case msgStepFinished:
+		return m.handleStepFinished(msg)
+
+	case msgExported:
+		m.showingModal = true
+		m.modalMessage = fmt.Sprintf("Saved to %s", msg.path)
+		return m, nil
+
 	case msgError:
 		m.loading = false
 		m.lastError = msg.message
@@ -155,11 +190,29 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleModalMode(msg)
 	}
 
+	if m.similarQuery != "" {
+		return m.handleSimilarPrompt(msg)
+	}
+
+	if m.search != nil {
+		return m.handleHistorySearch(msg)
+	}
+
+	if m.browser != nil {
+		return m.handleFavoritesBrowser(msg)
+	}
+
+	if m.tagInput != nil {
+		return m.handleTagPrompt(msg)
+	}
+
 	switch m.state.Mode {
 	case "input":
 		return m.handleInputMode(msg)
 	case "selection":
 		return m.handleSelectionMode(msg)
+	case "workflow":
+		return m.handleWorkflowMode(msg)
 	case "tutorial":
 		return m.handleTutorialMode(msg)
 	}
@@ -192,10 +245,35 @@ func (m *Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.loading = true
 		m.historyIndex = -1
+		m.state.Query = query
+		m.recordQuery(query)
+		if m.workflowMode {
+			return m, tea.Batch(m.queryWorkflow(query), m.spinner.Tick())
+		}
+		if similar, found := m.service.FindSimilar(query); found {
+			m.loading = false
+			m.similarQuery = similar
+			return m, nil
+		}
+		if response := m.service.FromFavorites(query); response != nil {
+			return m, m.annotate(msgResponse{response: response, fromFavorites: true})
+		}
 		return m, tea.Batch(m.queryCommand(query), m.spinner.Tick())
 
+	case "ctrl+t":
+		m.workflowMode = !m.workflowMode
+		return m, nil
+
+	case "ctrl+r":
+		m.openHistorySearch()
+		return m, nil
+
+	case "ctrl+f":
+		m.openFavorites()
+		return m, nil
+
 	case "up":
-		history := m.cache.GetSearchHistory()
+		history := m.history.Queries()
 		if len(history) > 0 {
 			if m.historyIndex == -1 {
 				m.historyIndex = len(history) - 1
@@ -207,7 +285,7 @@ func (m *Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "down":
-		history := m.cache.GetSearchHistory()
+		history := m.history.Queries()
 		if len(history) > 0 && m.historyIndex != -1 {
 			if m.historyIndex < len(history)-1 {
 				m.historyIndex++
@@ -226,11 +304,36 @@ func (m *Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// handleSimilarPrompt lets the user reuse the answer to a similar past
+// query or ask the new one
+func (m *Model) handleSimilarPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "y":
+		query := m.similarQuery
+		m.similarQuery = ""
+		m.loading = true
+		m.state.Query = query
+		m.textInput.SetValue(query)
+		return m, tea.Batch(m.queryCommand(query), m.spinner.Tick())
+
+	case "r", "n":
+		m.similarQuery = ""
+		m.loading = true
+		return m, tea.Batch(m.queryCommand(m.state.Query), m.spinner.Tick())
+
+	case "ctrl+c", "esc":
+		m.similarQuery = ""
+		return m, nil
+	}
+	return m, nil
+}
+
 func (m *Model) handleSelectionMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "esc":
 		m.state.Mode = "input"
 		m.state.Response = nil
+		m.fromFavorites = false
 		m.textInput.Focus()
 		m.lastError = ""
 		return m, nil
@@ -249,21 +352,56 @@ func (m *Model) handleSelectionMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.state.Response != nil && m.state.SelectedCommand < len(m.state.Response.Commands) {
 			cmd := m.state.Response.Commands[m.state.SelectedCommand]
 			// Copy command to clipboard or execute based on safety level
+			m.recordCommand(cmd.Text)
 			return m, m.executeCommand(cmd)
 		}
 
+	case "a":
+		// Re-query using only installed tools
+		unavailable := validate.UnavailableTools(m.state.Response)
+		if len(unavailable) > 0 && !m.loading {
+			m.loading = true
+			return m, tea.Batch(m.queryAlternatives(m.state.Query, unavailable), m.spinner.Tick())
+		}
+
+	case "s":
+		m.toggleFavorite()
+		return m, nil
+
+	case "r":
+		// Ask the API instead of answering from favorites
+		if m.fromFavorites && !m.loading {
+			m.loading = true
+			return m, tea.Batch(m.queryCommand(m.state.Query), m.spinner.Tick())
+		}
+
+	case "p":
+		// Keep this answer in the cache for good
+		pinned, err := m.service.TogglePin(m.state.Query)
+		if err != nil {
+			m.lastError = err.Error()
+			return m, nil
+		}
+		m.lastError = ""
+		m.showingModal = true
+		m.modalMessage = "Unpinned; the answer expires normally"
+		if pinned {
+			m.modalMessage = "Pinned; the answer never expires"
+		}
+		return m, nil
+
 	case "n":
 		// New query
 		m.state.Mode = "input"
 		m.state.Response = nil
+		m.fromFavorites = false
 		m.textInput.SetValue("")
 		m.textInput.Focus()
 		m.lastError = ""
 
 		// Refresh autocomplete suggestions
-		searchHistory := m.cache.GetSearchHistory()
-		if len(searchHistory) > 0 {
-			m.textInput.SetSuggestions(searchHistory)
+		if queries := m.history.Queries(); len(queries) > 0 {
+			m.textInput.SetSuggestions(queries)
 		}
 
 		return m, nil
@@ -284,27 +422,48 @@ func (m *Model) handleTutorialMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m *Model) queryCommand(query string) tea.Cmd {
 	return func() tea.Msg {
-		// Check cache first
-		if cached, found := m.cache.Get(query); found {
-			var response models.Response
-			if err := json.Unmarshal([]byte(cached), &response); err == nil {
-				return msgResponse{response: &response}
-			}
+		ctx := context.Background()
+		response, err := m.service.Query(ctx, query)
+		if err != nil {
+			return msgResponse{err: err}
 		}
+		return msgResponse{response: response}
+	}
+}
+
+// annotate checks an answer that needs no API call against the local
+// system, which runs man and --help, and then shows it
+func (m *Model) annotate(msg msgResponse) tea.Cmd {
+	return func() tea.Msg {
+		m.service.Annotate(msg.response)
+		return msg
+	}
+}
 
-		// Query Claude
+func (m *Model) queryAlternatives(query string, unavailable []string) tea.Cmd {
+	return func() tea.Msg {
 		ctx := context.Background()
-		response, err := m.client.QueryCommands(ctx, query)
+		response, err := m.service.QueryAlternatives(ctx, query, unavailable)
 		if err != nil {
 			return msgResponse{err: err}
 		}
+		return msgResponse{response: response}
+	}
+}
 
-		// Cache the response
-		if data, err := json.Marshal(response); err == nil {
-			m.cache.Set(query, string(data))
-		}
+// recordQuery adds query to the history; a failure only costs recall
+func (m *Model) recordQuery(query string) {
+	id, err := m.history.Add(query)
+	if err != nil {
+		m.lastError = fmt.Sprintf("Failed to record history: %v", err)
+	}
+	m.historyID = id
+}
 
-		return msgResponse{response: response}
+// recordCommand notes the command chosen for the current query
+func (m *Model) recordCommand(command string) {
+	if err := m.history.SetCommand(m.historyID, command); err != nil {
+		m.lastError = fmt.Sprintf("Failed to record history: %v", err)
 	}
 }
 
@@ -327,9 +486,17 @@ func (m *Model) View() string {
 	var baseView string
 	switch m.state.Mode {
 	case "input":
-		baseView = m.renderInputView()
+		if m.search != nil {
+			baseView = m.renderHistorySearch()
+		} else if m.browser != nil {
+			baseView = m.renderFavoritesBrowser()
+		} else {
+			baseView = m.renderInputView()
+		}
 	case "selection":
 		baseView = m.renderSelectionView()
+	case "workflow":
+		baseView = m.renderWorkflowView()
 	case "tutorial":
 		baseView = m.renderTutorialView()
 	}
@@ -349,6 +516,12 @@ func (m *Model) renderInputView() string {
 		Bold(true).
 		Foreground(lipgloss.Color("35")).
 		Render("Clify → Natural-Language → CLI commands")
+	if m.workflowMode {
+		title += lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33")).
+			Render(" [workflow]")
+	}
+	title += m.renderTarget()
 
 	b.WriteString(title)
 	b.WriteString("\n\n")
@@ -377,7 +550,15 @@ func (m *Model) renderInputView() string {
 	// Help text
 	helpStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240"))
-	b.WriteString(helpStyle.Render("Press Enter to search • Tab for autocomplete • Ctrl+C to quit"))
+	if m.similarQuery != "" {
+		similarStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33"))
+		b.WriteString(similarStyle.Render(fmt.Sprintf("Similar past query: %q", m.similarQuery)))
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("Enter to reuse its answer • R to ask the new query • Esc to edit"))
+		return b.String()
+	}
+	b.WriteString(helpStyle.Render("Press Enter to search • Tab for autocomplete • Ctrl+R history • Ctrl+F favorites • Ctrl+T workflow mode • Ctrl+C to quit"))
 
 	return b.String()
 }
@@ -394,6 +575,15 @@ func (m *Model) renderSelectionView() string {
 		Bold(true).
 		Foreground(lipgloss.Color("35")).
 		Render("Query Results")
+	if m.state.Response.Offline {
+		offlineStyle := lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("0")).
+			Background(lipgloss.Color("214")).
+			Padding(0, 1)
+		title += " " + offlineStyle.Render("OFFLINE")
+	}
+	title += m.renderTarget()
 	b.WriteString(title)
 	b.WriteString("\n\n")
 
@@ -428,6 +618,29 @@ func (m *Model) renderSelectionView() string {
 		// Render command
 		b.WriteString(fmt.Sprintf("%s ", icon))
 		b.WriteString(cmdStyle.Render(cmd.Text))
+		if cmd.Source == query.SourceTeam {
+			teamStyle := lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("0")).
+				Background(lipgloss.Color("35")).
+				Padding(0, 1)
+			b.WriteString(" " + teamStyle.Render("team"))
+		} else if cmd.Source != "" {
+			sourceStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214"))
+			b.WriteString(" " + sourceStyle.Render("["+cmd.Source+"]"))
+		}
+		if m.isFavorite(cmd.Text) {
+			starStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("220"))
+			b.WriteString(" " + starStyle.Render("★"))
+		}
+		if cmd.Repaired {
+			repairedStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245")).
+				Italic(true)
+			b.WriteString(" " + repairedStyle.Render("(repaired)"))
+		}
 		b.WriteString("\n")
 
 		// Description
@@ -439,21 +652,168 @@ func (m *Model) renderSelectionView() string {
 			b.WriteString("\n")
 		}
 
+		// Missing tools
+		for _, tool := range cmd.MissingTools {
+			missingStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214")).
+				MarginLeft(4)
+			warning := fmt.Sprintf("⚠ %s is not installed", tool)
+			if hint := validate.InstallHint(tool, m.packageMgr); hint != "" {
+				warning += fmt.Sprintf(" (install: %s)", hint)
+			}
+			b.WriteString(missingStyle.Render(warning))
+			b.WriteString("\n")
+		}
+
+		// Invalid shell syntax
+		if cmd.SyntaxError != "" {
+			syntaxStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("196")).
+				MarginLeft(4)
+			b.WriteString(syntaxStyle.Render("⚠ Syntax error: " + cmd.SyntaxError))
+			b.WriteString("\n")
+		}
+
+		// Undocumented flags
+		for _, warning := range cmd.FlagWarnings {
+			flagStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("214")).
+				MarginLeft(4)
+			b.WriteString(flagStyle.Render("⚠ " + warning))
+			b.WriteString("\n")
+		}
+
 		if i < len(m.state.Response.Commands)-1 {
 			b.WriteString("\n")
 		}
 	}
 
+	// Details of the highlighted command
+	if m.state.SelectedCommand < len(m.state.Response.Commands) {
+		if details := m.renderDetails(m.state.Response.Commands[m.state.SelectedCommand]); details != "" {
+			b.WriteString("\n\n")
+			b.WriteString(details)
+		}
+	}
+
 	b.WriteString("\n\n")
 
+	// Error display
+	if m.lastError != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %s", m.lastError)))
+		b.WriteString("\n\n")
+	}
+
+	// Loading state with spinner
+	if m.loading {
+		loadingStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("33"))
+		b.WriteString(loadingStyle.Render(fmt.Sprintf("%s Searching...", m.spinner.View())))
+		b.WriteString("\n\n")
+	}
+
+	// Usage and budget
+	usageStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	if usage := m.state.Response.Usage; usage != nil {
+		b.WriteString(usageStyle.Render(fmt.Sprintf("%s • %d in / %d out tokens • $%.4f", usage.Model, usage.InputTokens, usage.OutputTokens, usage.CostUSD)))
+	} else if m.state.Response.Offline {
+		b.WriteString(usageStyle.Render("Offline • not verified by the model • $0.0000"))
+	} else if m.fromFavorites {
+		b.WriteString(usageStyle.Render("Favorites • $0.0000"))
+	} else if m.service.IsPinned(m.state.Query) {
+		b.WriteString(usageStyle.Render("Cached • pinned • $0.0000"))
+	} else {
+		b.WriteString(usageStyle.Render("Cached • $0.0000"))
+	}
+	b.WriteString("\n")
+	if spent, limit, exceeded := m.service.BudgetStatus(); exceeded {
+		budgetStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214"))
+		b.WriteString(budgetStyle.Render(fmt.Sprintf("⚠ Monthly budget of $%.2f exceeded ($%.2f spent)", limit, spent)))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
 	// Help text
 	helpStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240"))
-	b.WriteString(helpStyle.Render("↑/↓ Navigate • Enter to select • N for new query • Esc to go back"))
+	if m.tagInput != nil {
+		b.WriteString(m.tagInput.View())
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("Enter to save to favorites • Esc to cancel"))
+		return b.String()
+	}
+	help := "↑/↓ Navigate • Enter to select • S to star • P to pin • N for new query • Esc to go back"
+	switch {
+	case m.fromFavorites:
+		help = "↑/↓ Navigate • Enter to select • S to unstar • R to ask the API • N for new query • Esc to go back"
+	case len(validate.UnavailableTools(m.state.Response)) > 0:
+		help = "↑/↓ Navigate • Enter to select • A for alternatives • S to star • P to pin • N for new query • Esc to go back"
+	}
+	b.WriteString(helpStyle.Render(help))
 
 	return b.String()
 }
 
+// renderTarget labels the active profile and the system commands are
+// generated for
+func (m *Model) renderTarget() string {
+	targetStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	var label string
+	if profile := m.service.Profile(); profile != "" {
+		label += lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Render(" • profile: " + profile)
+	}
+	target := m.service.Target()
+	if target.IsLocal() {
+		return label + targetStyle.Render(" • target: this machine")
+	}
+	return label + targetStyle.Render(" • target: "+target.String())
+}
+
+// renderDetails shows the model-reported metadata of a command in a bordered pane
+func (m *Model) renderDetails(cmd models.Command) string {
+	var lines []string
+	labelStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("35")).
+		Bold(true)
+	addList := func(label string, values []string) {
+		if len(values) > 0 {
+			lines = append(lines, labelStyle.Render(label+": ")+strings.Join(values, ", "))
+		}
+	}
+
+	if cmd.Confidence > 0 {
+		lines = append(lines, labelStyle.Render("Confidence: ")+fmt.Sprintf("%.0f%%", cmd.Confidence*100))
+	}
+	addList("Platforms", cmd.Platforms)
+	addList("Prerequisites", cmd.Prerequisites)
+	for _, warning := range cmd.Warnings {
+		lines = append(lines, labelStyle.Render("Warning: ")+warning)
+	}
+	addList("Docs", cmd.Docs)
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Foreground(lipgloss.Color("245")).
+		Padding(0, 1)
+	if m.viewport.width > 10 {
+		paneStyle = paneStyle.Width(m.viewport.width - 4)
+	}
+	return paneStyle.Render(strings.Join(lines, "\n"))
+}
+
 func (m *Model) renderTutorialView() string {
 	var b strings.Builder
 
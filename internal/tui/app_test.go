@@ -0,0 +1,351 @@
+package tui
+
+import (
+	"clify/internal/client"
+	"clify/internal/client/clienttest"
+	"clify/internal/config"
+	"clify/internal/query"
+	"clify/internal/snippets"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newTestModel(t *testing.T, fixtures map[string]string) *Model {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	for q, text := range fixtures {
+		schema := client.CommandResponseSchema()
+		if strings.Contains(text, `"steps"`) {
+			schema = client.WorkflowResponseSchema()
+		}
+		fixture := client.Fixture{
+			Request:  client.Request{Query: q, Schema: schema},
+			Response: clienttest.MessageBody(text, 10, 5),
+		}
+		if err := client.SaveFixture(dir, fixture); err != nil {
+			t.Fatalf("SaveFixture() error = %v", err)
+		}
+	}
+
+	claudeClient := client.NewClaudeClientWithProvider(client.NewReplayProvider(dir))
+	cache := config.NewCacheManager()
+	service := query.NewService(claudeClient, cache)
+	service.SetFavorites(config.NewFavorites())
+	model := NewModel(service, config.NewHistory(config.DefaultHistorySize))
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	return model
+}
+
+func TestQueryShowsSelection(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"list files": `{"explanation": "List files", "commands": [{"text": "ls -la", "description": "List all files", "confidence": 0.9, "platforms": ["linux", "macos"], "docs": ["ls(1)"]}]}`,
+	})
+
+	model.textInput.SetValue("list files")
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(model.queryCommand("list files")())
+
+	if model.state.Mode != "selection" {
+		t.Fatalf("Mode = %q, want selection (error: %s)", model.state.Mode, model.lastError)
+	}
+	view := model.View()
+	if !strings.Contains(view, "ls -la") || !strings.Contains(view, "List all files") {
+		t.Errorf("View() does not show the returned command:\n%s", view)
+	}
+	if !strings.Contains(view, "Confidence: 90%") || !strings.Contains(view, "ls(1)") {
+		t.Errorf("View() does not show the command details:\n%s", view)
+	}
+
+	// A repeated query is answered from the cache
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	model.Update(model.queryCommand("list files")())
+	if !strings.Contains(model.View(), "Cached") {
+		t.Errorf("repeated query was not served from the cache:\n%s", model.View())
+	}
+}
+
+func TestQueryErrorStaysInInput(t *testing.T) {
+	model := newTestModel(t, nil)
+
+	model.Update(model.queryCommand("unrecorded")())
+
+	if model.state.Mode != "input" {
+		t.Errorf("Mode = %q, want input", model.state.Mode)
+	}
+	if !strings.Contains(model.View(), "no fixture recorded") {
+		t.Errorf("View() does not show the error:\n%s", model.View())
+	}
+}
+
+func TestWorkflowStepThrough(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"set up venv": `{"explanation": "Create a venv", "steps": [
+			{"id": 1, "text": "python3 -m venv .venv", "description": "Create the venv", "depends_on": []},
+			{"id": 2, "text": ".venv/bin/pip install -r requirements.txt", "description": "Install", "depends_on": [1]}
+		]}`,
+	})
+
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	if !model.workflowMode {
+		t.Fatal("Ctrl+T did not enable workflow mode")
+	}
+	model.Update(model.queryWorkflow("set up venv")())
+
+	if model.state.Mode != "workflow" {
+		t.Fatalf("Mode = %q, want workflow (error: %s)", model.state.Mode, model.lastError)
+	}
+	if !strings.Contains(model.View(), "python3 -m venv .venv") {
+		t.Errorf("View() does not show the steps:\n%s", model.View())
+	}
+
+	// Step 2 cannot run before step 1
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(model.lastError, "depends on step 1") {
+		t.Errorf("lastError = %q, want a dependency error", model.lastError)
+	}
+
+	// Skipping step 1 unblocks step 2
+	model.Update(tea.KeyMsg{Type: tea.KeyUp})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if model.stepStatus[0] != stepSkipped || model.selectedStep != 1 {
+		t.Errorf("after skip: status %v, selected %d; want skipped and step 2 selected", model.stepStatus[0], model.selectedStep)
+	}
+	if err := model.checkDependencies(1); err != nil {
+		t.Errorf("checkDependencies() error = %v after skipping the dependency", err)
+	}
+}
+
+func TestEmptyWorkflowStaysInInput(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"do nothing": `{"explanation": "Nothing to do", "steps": []}`,
+	})
+
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	model.Update(model.queryWorkflow("do nothing")())
+	if model.state.Mode != "input" || model.lastError == "" {
+		t.Errorf("Mode = %q, error %q; want input with an error", model.state.Mode, model.lastError)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter}) // must not panic
+}
+
+func TestUnreachableProviderFallsBackOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// A closed server refuses connections
+	server := clienttest.NewServer()
+	server.Close()
+	provider := client.NewAnthropicProvider("test-key")
+	provider.Endpoint = server.URL
+
+	cache := config.NewCacheManager()
+	model := NewModel(query.NewService(client.NewClaudeClientWithProvider(provider), cache), config.NewHistory(config.DefaultHistorySize))
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	model.Update(model.queryCommand("list all files")())
+
+	if model.state.Mode != "selection" {
+		t.Fatalf("Mode = %q, want selection (error: %s)", model.state.Mode, model.lastError)
+	}
+	view := model.View()
+	if !strings.Contains(view, "OFFLINE") || !strings.Contains(view, "[catalog]") {
+		t.Errorf("View() does not label the offline results:\n%s", view)
+	}
+}
+
+func TestSimilarQueryOffersReuse(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"find all txt files in this directory": `{"explanation": "Find", "commands": [{"text": "find . -name '*.txt'", "description": "Find txt files"}]}`,
+	})
+	model.Update(model.queryCommand("find all txt files in this directory")())
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+
+	model.textInput.SetValue("find txt files in directory")
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(model.View(), `Similar past query: "find all txt files in this directory"`) {
+		t.Fatalf("View() does not offer the similar query:\n%s", model.View())
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("reusing the similar query did not start a query")
+	}
+	model.Update(model.queryCommand(model.state.Query)())
+	if model.state.Mode != "selection" || !strings.Contains(model.View(), "Cached") {
+		t.Errorf("reused answer was not served from the cache:\n%s", model.View())
+	}
+}
+
+func TestPinCachedAnswer(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"list files": `{"explanation": "List files", "commands": [{"text": "ls -la", "description": "List all files"}]}`,
+	})
+	model.state.Query = "list files"
+	model.Update(model.queryCommand("list files")())
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if !strings.Contains(model.View(), "Pinned") {
+		t.Fatalf("View() does not confirm the pin:\n%s", model.View())
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// The cached answer is now shown as pinned
+	model.Update(model.queryCommand("list files")())
+	if !strings.Contains(model.View(), "Cached • pinned") {
+		t.Errorf("View() does not show the pin:\n%s", model.View())
+	}
+}
+
+func TestHistoryRecallsInRecencyOrder(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"list files": `{"explanation": "List files", "commands": [{"text": "ls -la", "description": "List all files"}]}`,
+	})
+	for _, q := range []string{"show disk usage", "list files", "find big files"} {
+		model.history.Add(q)
+	}
+
+	// Asking again moves the query to the front of the recall order
+	model.textInput.SetValue("list files")
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(model.queryCommand("list files")())
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if entry := model.history.Entries()[2]; entry.Query != "list files" || entry.Command != "ls -la" {
+		t.Errorf("newest history entry = %+v, want list files with the chosen command", entry)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	var recalled []string
+	for range 3 {
+		model.Update(tea.KeyMsg{Type: tea.KeyUp})
+		recalled = append(recalled, model.textInput.Value())
+	}
+	want := []string{"list files", "find big files", "show disk usage"}
+	if strings.Join(recalled, ",") != strings.Join(want, ",") {
+		t.Errorf("Up recalled %v, want %v", recalled, want)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := model.textInput.Value(); got != "find big files" {
+		t.Errorf("Down recalled %q, want find big files", got)
+	}
+}
+
+func TestHistorySearchLoadsCachedAnswer(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"show disk usage": `{"explanation": "Disk usage", "commands": [{"text": "df -h", "description": "Free space"}, {"text": "du -sh *", "description": "Usage per entry"}]}`,
+	})
+	model.textInput.SetValue("show disk usage")
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(model.queryCommand("show disk usage")())
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model.history.Add("list files")
+
+	model.textInput.SetValue("")
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("du -s")})
+	view := model.View()
+	if !strings.Contains(view, "du -sh *") || strings.Contains(view, "list files") {
+		t.Fatalf("View() does not filter by the chosen command:\n%s", view)
+	}
+	if !strings.Contains(view, "just now") || !strings.Contains(view, "df -h") {
+		t.Errorf("View() lacks the timestamp or the cached preview:\n%s", view)
+	}
+
+	// The cached answer is annotated off the UI thread, without the API
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("loading from history returned no command")
+	}
+	msg, ok := cmd().(msgResponse)
+	if !ok || msg.err != nil || msg.response == nil {
+		t.Fatalf("loading from history = %+v, want the cached answer", msg)
+	}
+	model.Update(msg)
+	if model.state.Mode != "selection" || model.state.SelectedCommand != 1 {
+		t.Errorf("Mode = %q, SelectedCommand = %d, want selection of the chosen command", model.state.Mode, model.state.SelectedCommand)
+	}
+}
+
+func TestStarAndAnswerFromFavorites(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"show disk usage": `{"explanation": "Disk usage", "commands": [{"text": "df -h", "description": "Free space"}, {"text": "du -sh *", "description": "Usage per entry"}]}`,
+	})
+	model.state.Query = "show disk usage"
+	model.Update(model.queryCommand("show disk usage")())
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("disk, daily")})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	favorite, found := model.service.Favorites().Get("du -sh *")
+	if !found || favorite.Query != "show disk usage" || strings.Join(favorite.Tags, ",") != "disk,daily" {
+		t.Fatalf("saved favorite = %+v, %v", favorite, found)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(model.View(), "du -sh * ★") {
+		t.Errorf("View() does not mark the favorite:\n%s", model.View())
+	}
+
+	// A related query is answered from favorites without the API
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	model.textInput.SetValue("daily")
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	msg, ok := cmd().(msgResponse)
+	if !ok || !msg.fromFavorites {
+		t.Fatalf("query was not answered from favorites: %+v", msg)
+	}
+	model.Update(msg)
+	if model.state.Mode != "selection" || !strings.Contains(model.View(), "[favorite]") {
+		t.Fatalf("query was not answered from favorites:\n%s", model.View())
+	}
+
+	// The browser filters by tag and search
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if view := model.View(); !strings.Contains(view, "du -sh *") || !strings.Contains(view, "#daily") {
+		t.Errorf("browser does not list the favorite:\n%s", view)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("lsof")})
+	if view := model.View(); !strings.Contains(view, "No matching favorites") {
+		t.Errorf("browser does not filter:\n%s", view)
+	}
+}
+
+func TestTeamSnippetsShownFirst(t *testing.T) {
+	model := newTestModel(t, map[string]string{
+		"show disk usage": `{"explanation": "Disk usage", "commands": [{"text": "df -h", "description": "Free space"}, {"text": "du -sh *", "description": "Usage per entry"}]}`,
+	})
+	model.service.SetSnippets(&snippets.Index{Snippets: []snippets.Snippet{
+		{Query: "show disk usage", Command: "du -sh *", Description: "Team-approved usage report", SafetyLevel: "safe"},
+		{Query: "restart nginx", Command: "systemctl restart nginx"},
+	}})
+
+	model.state.Query = "show disk usage"
+	model.Update(model.queryCommand("show disk usage")())
+
+	commands := model.state.Response.Commands
+	if len(commands) != 2 || commands[0].Text != "du -sh *" || commands[0].Source != query.SourceTeam {
+		t.Fatalf("Commands = %+v, want the team snippet first and no duplicate", commands)
+	}
+	if view := model.View(); !strings.Contains(view, "du -sh *  team") || !strings.Contains(view, "Team-approved") {
+		t.Errorf("View() does not badge the team snippet:\n%s", view)
+	}
+}
+
+func TestHeaderShowsProfile(t *testing.T) {
+	m := newTestModel(t, nil)
+	if view := m.View(); strings.Contains(view, "profile:") {
+		t.Errorf("header shows a profile without one:\n%s", view)
+	}
+
+	m.service.SetProfile("work")
+	if view := m.View(); !strings.Contains(view, "profile: work") {
+		t.Errorf("header does not show the active profile:\n%s", view)
+	}
+}
@@ -0,0 +1,314 @@
+package tui
+
+import (
+	"clify/internal/config"
+	"clify/internal/fuzzy"
+	"clify/internal/models"
+	"clify/internal/query"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// favoritesBrowser is the state of the Ctrl+F favorites overlay
+type favoritesBrowser struct {
+	input    textinput.Model
+	tags     []string
+	tag      int // index into tags, or -1 for all
+	matches  []favoriteMatch
+	selected int
+}
+
+type favoriteMatch struct {
+	favorite  models.Favorite
+	positions []int // matched runes of the command
+}
+
+// toggleFavorite removes the highlighted command from the favorites, or
+// asks for tags to save it with
+func (m *Model) toggleFavorite() {
+	favorites := m.service.Favorites()
+	if favorites == nil || m.state.Response == nil || m.state.SelectedCommand >= len(m.state.Response.Commands) {
+		return
+	}
+
+	cmd := m.state.Response.Commands[m.state.SelectedCommand]
+	if _, found := favorites.Get(cmd.Text); found {
+		if _, err := favorites.Remove(cmd.Text); err != nil {
+			m.lastError = err.Error()
+			return
+		}
+		m.showingModal = true
+		m.modalMessage = "Removed from favorites"
+		return
+	}
+
+	ti := textinput.New()
+	ti.Prompt = "Tags: "
+	ti.Placeholder = "comma-separated, optional"
+	ti.Focus()
+	ti.Width = m.textInput.Width
+	m.tagInput = &ti
+}
+
+func (m *Model) handleTagPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.tagInput = nil
+		return m, nil
+
+	case "enter":
+		cmd := m.state.Response.Commands[m.state.SelectedCommand]
+		favorite := models.Favorite{
+			Command:     cmd.Text,
+			Query:       m.state.Query,
+			Description: cmd.Description,
+			Tags:        config.ParseTags(m.tagInput.Value()),
+			SafetyLevel: cmd.SafetyLevel,
+			Added:       time.Now(),
+		}
+		m.tagInput = nil
+		if err := m.service.Favorites().Add(favorite); err != nil {
+			m.lastError = err.Error()
+			return m, nil
+		}
+		m.lastError = ""
+		m.showingModal = true
+		m.modalMessage = "★ Saved to favorites"
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	*m.tagInput, cmd = m.tagInput.Update(msg)
+	return m, cmd
+}
+
+// isFavorite reports whether command is saved in the favorites
+func (m *Model) isFavorite(command string) bool {
+	if m.service.Favorites() == nil {
+		return false
+	}
+	_, found := m.service.Favorites().Get(command)
+	return found
+}
+
+// showFavorites answers the current query from favorites without the API
+func (m *Model) showFavorites(response *models.Response) {
+	m.state.Response = response
+	m.state.Mode = "selection"
+	m.state.SelectedCommand = 0
+	m.fromFavorites = true
+	m.textInput.Blur()
+	m.lastError = ""
+}
+
+func (m *Model) openFavorites() {
+	favorites := m.service.Favorites()
+	if favorites == nil {
+		return
+	}
+
+	ti := textinput.New()
+	ti.Prompt = "Search: "
+	ti.Placeholder = "command, query, description or tag"
+	ti.Focus()
+	ti.Width = m.textInput.Width
+
+	m.browser = &favoritesBrowser{input: ti, tags: favorites.Tags(), tag: -1}
+	m.filterFavorites()
+}
+
+// filterFavorites lists the favorites with the selected tag that match the
+// search, best first and newest first among equal scores
+func (m *Model) filterFavorites() {
+	pattern := m.browser.input.Value()
+	favorites := m.service.Favorites().List()
+
+	type scored struct {
+		match favoriteMatch
+		score int
+	}
+	var matches []scored
+	for i := len(favorites) - 1; i >= 0; i-- {
+		favorite := favorites[i]
+		if m.browser.tag >= 0 && !config.HasTag(favorite, m.browser.tags[m.browser.tag]) {
+			continue
+		}
+
+		// One text so that terms may match different fields; only the
+		// command part is highlighted
+		text := strings.Join(append([]string{favorite.Command, favorite.Query, favorite.Description}, favorite.Tags...), "  ")
+		score, positions, ok := fuzzy.Subsequence(pattern, text)
+		if !ok {
+			continue
+		}
+		commandLen := len([]rune(favorite.Command))
+		var commandPositions []int
+		for _, pos := range positions {
+			if pos < commandLen {
+				commandPositions = append(commandPositions, pos)
+			}
+		}
+		matches = append(matches, scored{favoriteMatch{favorite, commandPositions}, score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	m.browser.matches = m.browser.matches[:0]
+	for _, s := range matches {
+		m.browser.matches = append(m.browser.matches, s.match)
+	}
+	m.browser.selected = min(m.browser.selected, max(0, len(m.browser.matches)-1))
+}
+
+func (m *Model) handleFavoritesBrowser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.browser = nil
+		return m, nil
+
+	case "up":
+		if m.browser.selected > 0 {
+			m.browser.selected--
+		}
+		return m, nil
+
+	case "down":
+		if m.browser.selected < len(m.browser.matches)-1 {
+			m.browser.selected++
+		}
+		return m, nil
+
+	case "tab":
+		// Cycle through the tags, then back to all
+		m.browser.tag++
+		if m.browser.tag >= len(m.browser.tags) {
+			m.browser.tag = -1
+		}
+		m.browser.selected = 0
+		m.filterFavorites()
+		return m, nil
+
+	case "ctrl+d":
+		if len(m.browser.matches) == 0 {
+			return m, nil
+		}
+		favorite := m.browser.matches[m.browser.selected].favorite
+		if _, err := m.service.Favorites().Remove(favorite.Command); err != nil {
+			m.lastError = err.Error()
+			return m, nil
+		}
+		m.filterFavorites()
+		return m, nil
+
+	case "enter":
+		if len(m.browser.matches) == 0 {
+			return m, nil
+		}
+		favorite := m.browser.matches[m.browser.selected].favorite
+		m.browser = nil
+		m.state.Query = favorite.Query
+		m.textInput.SetValue(favorite.Query)
+		m.showFavorites(query.FavoritesResponse([]models.Favorite{favorite}))
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.browser.input, cmd = m.browser.input.Update(msg)
+	m.browser.selected = 0
+	m.filterFavorites()
+	return m, cmd
+}
+
+func (m *Model) renderFavoritesBrowser() string {
+	var b strings.Builder
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("35")).
+		Render("★ Favorites")
+	b.WriteString(title)
+	b.WriteString("\n\n")
+	b.WriteString(m.browser.input.View())
+	b.WriteString("\n")
+
+	// Tag filter
+	tagStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	activeTagStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("35")).
+		Bold(true)
+	tags := []string{"all"}
+	for _, tag := range m.browser.tags {
+		tags = append(tags, "#"+tag)
+	}
+	for i, tag := range tags {
+		style := tagStyle
+		if i-1 == m.browser.tag {
+			style = activeTagStyle
+		}
+		b.WriteString(style.Render(tag) + " ")
+	}
+	b.WriteString("\n\n")
+
+	if m.lastError != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %s", m.lastError)))
+		b.WriteString("\n\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	if len(m.browser.matches) == 0 {
+		b.WriteString(helpStyle.Render("No matching favorites. Press S on a result to save one."))
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("Tab to filter by tag • Esc to go back"))
+		return b.String()
+	}
+
+	descStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245")).
+		MarginLeft(4)
+	first := max(0, m.browser.selected-searchVisible+1)
+	last := min(len(m.browser.matches), first+searchVisible)
+	for i := first; i < last; i++ {
+		match := m.browser.matches[i]
+		selected := i == m.browser.selected
+
+		marker := "  "
+		if selected {
+			marker = "> "
+		}
+		icon := m.classifier.GetSafetyIcon(models.SafetyLevel(match.favorite.SafetyLevel))
+		b.WriteString(marker + icon + " ")
+		b.WriteString(highlight(match.favorite.Command, match.positions, selected))
+		if len(match.favorite.Tags) > 0 {
+			b.WriteString(" " + tagStyle.Render("#"+strings.Join(match.favorite.Tags, " #")))
+		}
+		b.WriteString("\n")
+
+		var details []string
+		if match.favorite.Description != "" {
+			details = append(details, match.favorite.Description)
+		}
+		if match.favorite.Query != "" {
+			details = append(details, fmt.Sprintf("for %q", match.favorite.Query))
+		}
+		if len(details) > 0 {
+			b.WriteString(descStyle.Render(strings.Join(details, " • ")))
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("↑/↓ Navigate • Enter to open • Tab to filter by tag • Ctrl+D to remove • Esc to go back"))
+	return b.String()
+}
@@ -0,0 +1,263 @@
+package tui
+
+import (
+	"clify/internal/fuzzy"
+	"clify/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// searchVisible is the number of matches listed at once
+const searchVisible = 8
+
+// searchMatch is a history entry matching the reverse-search pattern, on
+// its query or on the command chosen for it
+type searchMatch struct {
+	entry     models.HistoryEntry
+	onCommand bool  // the command matched better than the query
+	positions []int // matched runes of the query or command
+	score     int
+}
+
+// historySearch is the state of the Ctrl+R overlay
+type historySearch struct {
+	input    textinput.Model
+	matches  []searchMatch
+	selected int
+}
+
+func (m *Model) openHistorySearch() {
+	ti := textinput.New()
+	ti.Prompt = "(reverse-i-search) "
+	ti.Placeholder = "type to filter past queries and commands"
+	ti.SetValue(m.textInput.Value())
+	ti.Focus()
+	ti.Width = m.textInput.Width
+
+	m.search = &historySearch{input: ti}
+	m.filterHistory()
+}
+
+// filterHistory lists the entries matching the pattern, best first and
+// newest first among equal scores
+func (m *Model) filterHistory() {
+	pattern := m.search.input.Value()
+	entries := m.history.Entries()
+
+	var matches []searchMatch
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		score, positions, ok := fuzzy.Subsequence(pattern, entry.Query)
+		match := searchMatch{entry: entry, positions: positions, score: score}
+		if entry.Command != "" {
+			if cmdScore, cmdPositions, cmdOK := fuzzy.Subsequence(pattern, entry.Command); cmdOK && (!ok || cmdScore > score) {
+				match = searchMatch{entry: entry, onCommand: true, positions: cmdPositions, score: cmdScore}
+				ok = true
+			}
+		}
+		if ok {
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	m.search.matches = matches
+	m.search.selected = 0
+}
+
+func (m *Model) handleHistorySearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc", "ctrl+g":
+		m.search = nil
+		return m, nil
+
+	case "ctrl+r", "up", "ctrl+p":
+		if m.search.selected < len(m.search.matches)-1 {
+			m.search.selected++
+		}
+		return m, nil
+
+	case "down", "ctrl+n":
+		if m.search.selected > 0 {
+			m.search.selected--
+		}
+		return m, nil
+
+	case "enter":
+		if len(m.search.matches) == 0 {
+			return m, nil
+		}
+		match := m.search.matches[m.search.selected]
+		m.search = nil
+		return m, m.loadFromHistory(match.entry)
+	}
+
+	var cmd tea.Cmd
+	m.search.input, cmd = m.search.input.Update(msg)
+	m.filterHistory()
+	return m, cmd
+}
+
+// loadFromHistory shows the cached answer to a past query, highlighting the
+// command chosen last time. Without a cached answer the query is put in the
+// prompt to ask again.
+func (m *Model) loadFromHistory(entry models.HistoryEntry) tea.Cmd {
+	m.textInput.SetValue(entry.Query)
+	m.textInput.CursorEnd()
+	m.lastError = ""
+
+	response, found := m.service.Cached(entry.Query)
+	if !found {
+		return nil
+	}
+
+	m.state.Query = entry.Query
+	m.loading = true
+	m.recordQuery(entry.Query)
+	return m.annotate(msgResponse{response: response, command: entry.Command})
+}
+
+func (m *Model) renderHistorySearch() string {
+	var b strings.Builder
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("35")).
+		Render("History Search")
+	b.WriteString(title)
+	b.WriteString("\n\n")
+	b.WriteString(m.search.input.View())
+	b.WriteString("\n\n")
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	if len(m.search.matches) == 0 {
+		b.WriteString(helpStyle.Render("No matching history"))
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("Esc to go back"))
+		return b.String()
+	}
+
+	// Keep the selection in view, newest matches at the top
+	first := max(0, m.search.selected-searchVisible+1)
+	last := min(len(m.search.matches), first+searchVisible)
+
+	timeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	commandStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245"))
+	for i := first; i < last; i++ {
+		match := m.search.matches[i]
+		selected := i == m.search.selected
+
+		marker := "  "
+		if selected {
+			marker = "> "
+		}
+		b.WriteString(marker)
+		b.WriteString(timeStyle.Render(fmt.Sprintf("%-8s ", ago(match.entry.Timestamp, time.Now()))))
+		if match.onCommand {
+			b.WriteString(highlight(match.entry.Query, nil, selected))
+			b.WriteString(commandStyle.Render("  → "))
+			b.WriteString(highlight(match.entry.Command, match.positions, selected))
+		} else {
+			b.WriteString(highlight(match.entry.Query, match.positions, selected))
+			if match.entry.Command != "" {
+				b.WriteString(commandStyle.Render("  → " + match.entry.Command))
+			}
+		}
+		b.WriteString("\n")
+	}
+	if len(m.search.matches) > searchVisible {
+		b.WriteString(helpStyle.Render(fmt.Sprintf("  %d of %d matches", m.search.selected+1, len(m.search.matches))))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.renderHistoryPreview(m.search.matches[m.search.selected].entry))
+	b.WriteString("\n\n")
+	b.WriteString(helpStyle.Render("Ctrl+R/↑ older • ↓ newer • Enter to load • Esc to go back"))
+
+	return b.String()
+}
+
+// renderHistoryPreview shows the cached answer to the highlighted entry
+func (m *Model) renderHistoryPreview(entry models.HistoryEntry) string {
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Foreground(lipgloss.Color("245")).
+		Padding(0, 1)
+	if m.viewport.width > 10 {
+		paneStyle = paneStyle.Width(m.viewport.width - 4)
+	}
+
+	response, found := m.service.Preview(entry.Query)
+	if !found {
+		return paneStyle.Render("No cached answer; Enter puts the query in the prompt to ask again")
+	}
+
+	var lines []string
+	if response.Explanation != "" {
+		lines = append(lines, response.Explanation)
+	}
+	for _, cmd := range response.Commands {
+		icon := m.classifier.GetSafetyIcon(models.SafetyLevel(cmd.SafetyLevel))
+		line := fmt.Sprintf("%s %s", icon, cmd.Text)
+		if cmd.Text == entry.Command {
+			line += " (chosen)"
+		}
+		lines = append(lines, line)
+	}
+	return paneStyle.Render(strings.Join(lines, "\n"))
+}
+
+// highlight renders text with the runes at positions emphasized
+func highlight(text string, positions []int, selected bool) string {
+	plain := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("15"))
+	if selected {
+		plain = plain.Bold(true)
+	}
+	matched := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("35")).
+		Bold(true).
+		Underline(true)
+
+	var b strings.Builder
+	next := 0
+	for i, r := range []rune(text) {
+		if next < len(positions) && positions[next] == i {
+			b.WriteString(matched.Render(string(r)))
+			next++
+		} else {
+			b.WriteString(plain.Render(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// ago formats how long before now t was, e.g. "5m ago"
+func ago(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	default:
+		return t.Format("2006-01-02")
+	}
+}
@@ -37,4 +37,4 @@ func (s *Spinner) Update(msg tea.Msg) tea.Cmd {
 		return s.Tick()
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,307 @@
+package tui
+
+import (
+	"clify/internal/models"
+	"clify/internal/workflow"
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type stepStatus int
+
+const (
+	stepPending stepStatus = iota
+	stepDone
+	stepFailed
+	stepSkipped
+)
+
+type msgWorkflow struct {
+	workflow *models.Workflow
+	err      error
+}
+
+type msgStepFinished struct {
+	index int
+	err   error
+}
+
+type msgExported struct {
+	path string
+}
+
+// SetWorkflowMode makes queries return ordered workflows instead of alternatives
+func (m *Model) SetWorkflowMode(enabled bool) {
+	m.workflowMode = enabled
+}
+
+func (m *Model) queryWorkflow(query string) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		wf, err := m.service.QueryWorkflow(ctx, query)
+		return msgWorkflow{workflow: wf, err: err}
+	}
+}
+
+func (m *Model) handleWorkflowMsg(msg msgWorkflow) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		m.lastError = msg.err.Error()
+		return m, nil
+	}
+
+	if msg.workflow == nil || len(msg.workflow.Steps) == 0 {
+		m.lastError = "No steps found for this query"
+		return m, nil
+	}
+
+	m.workflow = msg.workflow
+	m.stepStatus = make([]stepStatus, len(msg.workflow.Steps))
+	m.selectedStep = 0
+	m.confirmStep = false
+	m.state.Mode = "workflow"
+	m.textInput.Blur()
+	m.lastError = ""
+	return m, nil
+}
+
+func (m *Model) handleStepFinished(msg msgStepFinished) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.stepStatus[msg.index] = stepFailed
+		m.lastError = fmt.Sprintf("Step %d failed: %v", m.workflow.Steps[msg.index].ID, msg.err)
+		return m, nil
+	}
+
+	m.stepStatus[msg.index] = stepDone
+	m.lastError = ""
+	m.selectNextPendingStep()
+	return m, nil
+}
+
+func (m *Model) handleWorkflowMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.workflow == nil {
+		return m, nil
+	}
+
+	// A dangerous step waits for explicit confirmation
+	if m.confirmStep {
+		m.confirmStep = false
+		if msg.String() == "y" {
+			return m, m.runStep(m.selectedStep)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		m.state.Mode = "input"
+		m.workflow = nil
+		m.textInput.Focus()
+		m.lastError = ""
+		return m, nil
+
+	case "up":
+		if m.selectedStep > 0 {
+			m.selectedStep--
+		}
+
+	case "down":
+		if m.selectedStep < len(m.workflow.Steps)-1 {
+			m.selectedStep++
+		}
+
+	case "enter", "r":
+		if err := m.checkDependencies(m.selectedStep); err != nil {
+			m.lastError = err.Error()
+			return m, nil
+		}
+		step := m.workflow.Steps[m.selectedStep]
+		if step.SafetyLevel == string(models.SafetyLevelDangerous) {
+			m.confirmStep = true
+			return m, nil
+		}
+		return m, m.runStep(m.selectedStep)
+
+	case "s":
+		m.stepStatus[m.selectedStep] = stepSkipped
+		m.lastError = ""
+		m.selectNextPendingStep()
+
+	case "c":
+		step := m.workflow.Steps[m.selectedStep]
+		return m, m.executeCommand(models.Command{Text: step.Text})
+
+	case "e":
+		return m, m.exportWorkflow()
+	}
+
+	return m, nil
+}
+
+// checkDependencies returns an error if a step this one depends on has not
+// completed or been skipped
+func (m *Model) checkDependencies(index int) error {
+	for _, dep := range m.workflow.Steps[index].DependsOn {
+		for i, step := range m.workflow.Steps {
+			if step.ID != dep {
+				continue
+			}
+			if m.stepStatus[i] != stepDone && m.stepStatus[i] != stepSkipped {
+				return fmt.Errorf("step %d depends on step %d, which has not completed", m.workflow.Steps[index].ID, dep)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Model) selectNextPendingStep() {
+	for i := m.selectedStep + 1; i < len(m.stepStatus); i++ {
+		if m.stepStatus[i] == stepPending {
+			m.selectedStep = i
+			return
+		}
+	}
+}
+
+// runStep suspends the TUI and runs the step in the user's terminal, after
+// the steps it depends on that set up the shell, such as activating a
+// virtual environment
+func (m *Model) runStep(index int) tea.Cmd {
+	setup := workflow.Setup(m.workflow, index, func(i int) bool { return m.stepStatus[i] == stepDone })
+	cmd := workflow.StepCommand(m.stepShell(), m.workflow.Steps[index].Text, setup)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return msgStepFinished{index: index, err: err}
+	})
+}
+
+// stepShell is the shell steps run in: the target's, powershell on
+// Windows, or "" for bash
+func (m *Model) stepShell() string {
+	if shell := m.service.Target().Shell; shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return ""
+}
+
+func (m *Model) exportWorkflow() tea.Cmd {
+	query := m.state.Query
+	wf := m.workflow
+	return func() tea.Msg {
+		path, err := workflow.ExportNew(query, wf)
+		if err != nil {
+			return msgError{message: err.Error()}
+		}
+		return msgExported{path: path}
+	}
+}
+
+func (m *Model) renderWorkflowView() string {
+	if m.workflow == nil {
+		return "No workflow available"
+	}
+
+	var b strings.Builder
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("35")).
+		Render("Workflow")
+	b.WriteString(title)
+	b.WriteString("\n\n")
+
+	if m.workflow.Explanation != "" {
+		explanationStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245"))
+		b.WriteString(explanationStyle.Render(m.workflow.Explanation))
+		b.WriteString("\n\n")
+	}
+
+	for i, step := range m.workflow.Steps {
+		selected := i == m.selectedStep
+		icon := m.classifier.GetSafetyIcon(models.SafetyLevel(step.SafetyLevel))
+
+		var cmdStyle lipgloss.Style
+		if selected {
+			cmdStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("240")).
+				Foreground(lipgloss.Color("15")).
+				Bold(true)
+		} else {
+			cmdStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("15"))
+		}
+
+		b.WriteString(fmt.Sprintf("%s %s %d. ", statusMark(m.stepStatus[i]), icon, step.ID))
+		b.WriteString(cmdStyle.Render(step.Text))
+		b.WriteString("\n")
+
+		descStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245")).
+			MarginLeft(8)
+		desc := step.Description
+		if len(step.DependsOn) > 0 {
+			deps := make([]string, len(step.DependsOn))
+			for j, dep := range step.DependsOn {
+				deps[j] = fmt.Sprintf("%d", dep)
+			}
+			desc += fmt.Sprintf(" (after %s)", strings.Join(deps, ", "))
+		}
+		if workflow.ChangesShellState(step.Text) {
+			// Each step runs in a new shell
+			if workflow.CarriesState(m.stepShell()) {
+				desc += " (applies to the steps that depend on it)"
+			} else {
+				desc += " (does not carry over to later steps)"
+			}
+		}
+		if desc != "" {
+			b.WriteString(descStyle.Render(desc))
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+
+	if m.confirmStep {
+		confirmStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		b.WriteString(confirmStyle.Render("This step is dangerous. Press Y to run it, any other key to cancel."))
+		b.WriteString("\n\n")
+	}
+
+	if m.lastError != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true)
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %s", m.lastError)))
+		b.WriteString("\n\n")
+	}
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	b.WriteString(helpStyle.Render("↑/↓ Navigate • Enter to run • S to skip • C to copy • E to export script • Esc to go back"))
+
+	return b.String()
+}
+
+func statusMark(status stepStatus) string {
+	switch status {
+	case stepDone:
+		return "✓"
+	case stepFailed:
+		return "✗"
+	case stepSkipped:
+		return "↷"
+	default:
+		return "·"
+	}
+}
@@ -0,0 +1,196 @@
+package validate
+
+import (
+	"bytes"
+	"clify/internal/models"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// helpTimeout bounds how long a man page or --help lookup may take
+const helpTimeout = 3 * time.Second
+
+// noHelpFallback lists executables never run with --help since some
+// implementations ignore unknown flags and perform their action anyway
+var noHelpFallback = map[string]bool{
+	"shutdown": true, "reboot": true, "halt": true, "poweroff": true,
+	"rm": true, "dd": true, "mkfs": true, "fdisk": true, "kill": true,
+	"killall": true, "pkill": true, "format": true,
+}
+
+var overstrike = regexp.MustCompile(".\x08")
+
+// FlagVerifier checks command flags against local documentation
+type FlagVerifier struct {
+	mu    sync.Mutex
+	help  map[string]string
+	fetch func(executable string) string
+}
+
+func NewFlagVerifier() *FlagVerifier {
+	return &FlagVerifier{
+		help:  make(map[string]string),
+		fetch: fetchHelpText,
+	}
+}
+
+// AnnotateFlagWarnings records undocumented flags on every command in response
+func (v *FlagVerifier) AnnotateFlagWarnings(response *models.Response) {
+	if response == nil {
+		return
+	}
+	for i := range response.Commands {
+		response.Commands[i].FlagWarnings = v.FlagWarnings(response.Commands[i].Text)
+	}
+}
+
+// FlagWarnings returns a warning for each flag that does not appear in the
+// man page or --help output of the executable it is passed to. Executables
+// without local documentation are skipped.
+func (v *FlagVerifier) FlagWarnings(command string) []string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil
+	}
+
+	var warnings []string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+
+		name := call.Args[0].Lit()
+		if name == "" || shellBuiltins[name] || wrapperCommands[name] {
+			return true
+		}
+
+		help := v.helpText(name)
+		if help == "" {
+			return true
+		}
+
+		for _, flag := range callFlags(call) {
+			if !flagDocumented(help, flag) {
+				warnings = append(warnings, fmt.Sprintf("%s is not documented for %s", flag, name))
+			}
+		}
+		return true
+	})
+
+	return warnings
+}
+
+func (v *FlagVerifier) helpText(executable string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if text, ok := v.help[executable]; ok {
+		return text
+	}
+	text := v.fetch(executable)
+	v.help[executable] = text
+	return text
+}
+
+// callFlags returns the literal flags passed to a call, up to a "--" marker
+func callFlags(call *syntax.CallExpr) []string {
+	var flags []string
+	for _, arg := range call.Args[1:] {
+		word := arg.Lit()
+		if word == "--" {
+			break
+		}
+		if len(word) < 2 || word[0] != '-' || isNumeric(word[1:]) {
+			continue
+		}
+		if name, _, ok := strings.Cut(word, "="); ok {
+			word = name
+		}
+		flags = append(flags, word)
+	}
+	return flags
+}
+
+// flagDocumented reports whether flag appears in help. Short flag clusters
+// such as -la are accepted when every letter is documented on its own.
+func flagDocumented(help, flag string) bool {
+	if mentions(help, flag) {
+		return true
+	}
+	if strings.HasPrefix(flag, "--") || len(flag) <= 2 {
+		return false
+	}
+	for _, letter := range flag[1:] {
+		if !mentions(help, "-"+string(letter)) {
+			return false
+		}
+	}
+	return true
+}
+
+func mentions(help, flag string) bool {
+	pattern := `(^|[^\w-])` + regexp.QuoteMeta(flag) + `($|[^\w-])`
+	matched, _ := regexp.MatchString(pattern, help)
+	return matched
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// fetchHelpText reads the man page of executable, falling back to --help.
+// Only programs on $PATH are looked at; a path such as ./build.sh may be
+// anything, and running it with --help could do anything.
+func fetchHelpText(executable string) string {
+	if strings.ContainsAny(executable, `/\`) {
+		return ""
+	}
+	path, err := lookPath(executable)
+	if err != nil {
+		return ""
+	}
+
+	if text := runHelp("man", "-P", "cat", executable); text != "" {
+		return text
+	}
+	if noHelpFallback[executable] {
+		return ""
+	}
+	return runHelp(path, "--help")
+}
+
+func runHelp(name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), helpTimeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "MANWIDTH=200", "MAN_KEEP_FORMATTING=")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil && out.Len() == 0 {
+		return ""
+	}
+
+	return normalizeHelp(out.String())
+}
+
+// normalizeHelp strips man page overstrike formatting and typographic hyphens
+func normalizeHelp(text string) string {
+	text = overstrike.ReplaceAllString(text, "")
+	return strings.NewReplacer("‐", "-", "‑", "-", "−", "-").Replace(text)
+}
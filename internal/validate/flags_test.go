@@ -0,0 +1,74 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+const lsHelp = `
+     -a      Include directory entries whose names begin with a dot.
+     -l      List in long format.
+     -h      Use unit suffixes.
+`
+
+const sedHelp = `
+     -E      Interpret regular expressions as extended.
+     -i extension
+             Edit files in-place.
+`
+
+func TestFlagWarnings(t *testing.T) {
+	verifier := NewFlagVerifier()
+	verifier.fetch = func(executable string) string {
+		switch executable {
+		case "ls":
+			return lsHelp
+		case "sed":
+			return sedHelp
+		}
+		return ""
+	}
+
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"ls -la", nil},
+		{"ls -lah | head -20", nil},
+		{"sed --in-place 's/a/b/' file", []string{"--in-place is not documented for sed"}},
+		{"ls -lz", []string{"-lz is not documented for ls"}},
+		{"undocumented --anything", nil},
+		{"ls -l -- -x", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			result := verifier.FlagWarnings(tt.command)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("FlagWarnings(%q) = %v, want %v", tt.command, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeHelp(t *testing.T) {
+	result := normalizeHelp("-\x08--\x08-a\x08al\x08ll\x08l ‐r")
+	if result != "--all -r" {
+		t.Errorf("normalizeHelp() = %q, want %q", result, "--all -r")
+	}
+}
+
+func TestFetchHelpTextSkipsPaths(t *testing.T) {
+	looked := false
+	lookPath = func(name string) (string, error) {
+		looked = true
+		return name, nil
+	}
+	defer func() { lookPath = defaultLookPath }()
+
+	for _, name := range []string{"./build.sh", "/tmp/x", `scripts\deploy.ps1`} {
+		if text := fetchHelpText(name); text != "" || looked {
+			t.Errorf("fetchHelpText(%q) = %q, looked up %v; want it skipped", name, text, looked)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ValidateSchema checks data against the "schema" member of a structured
+// output schema such as command_response_schema.json. It supports the subset
+// of JSON Schema used there: type, properties, required,
+// additionalProperties, items, minimum and maximum.
+func ValidateSchema(schemaJSON, data string) ([]string, error) {
+	var wrapper struct {
+		Schema map[string]interface{} `json:"schema"`
+	}
+	if err := json.Unmarshal([]byte(schemaJSON), &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return []string{fmt.Sprintf("response is not valid JSON: %v", err)}, nil
+	}
+
+	var problems []string
+	checkValue(wrapper.Schema, value, "$", &problems)
+	return problems, nil
+}
+
+func checkValue(schema map[string]interface{}, value interface{}, path string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			report("expected an object")
+			return
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, present := obj[name.(string)]; !present {
+					report("missing required property %q", name)
+				}
+			}
+		}
+
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propSchema, known := properties[name].(map[string]interface{})
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					report("unexpected property %q", name)
+				}
+				continue
+			}
+			checkValue(propSchema, obj[name], path+"."+name, problems)
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			report("expected an array")
+			return
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				checkValue(itemSchema, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+
+	case "string":
+		if _, ok := value.(string); !ok {
+			report("expected a string")
+		}
+
+	case "number", "integer":
+		number, ok := value.(float64)
+		if !ok {
+			report("expected a number")
+			return
+		}
+		if schema["type"] == "integer" && number != float64(int64(number)) {
+			report("expected an integer")
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && number < minimum {
+			report("must be at least %v", minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && number > maximum {
+			report("must be at most %v", maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			report("expected a boolean")
+		}
+	}
+}
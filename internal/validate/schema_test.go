@@ -0,0 +1,55 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+	"name": "test",
+	"schema": {
+		"type": "object",
+		"properties": {
+			"items": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"text": {"type": "string"},
+						"confidence": {"type": "number", "minimum": 0, "maximum": 1}
+					},
+					"required": ["text"],
+					"additionalProperties": false
+				}
+			}
+		},
+		"required": ["items"],
+		"additionalProperties": false
+	}
+}`
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected []string
+	}{
+		{`{"items": [{"text": "ls", "confidence": 0.5}]}`, nil},
+		{`{}`, []string{`$: missing required property "items"`}},
+		{`{"items": [{"confidence": 2, "extra": true}]}`, []string{
+			`$.items[0]: missing required property "text"`,
+			`$.items[0].confidence: must be at most 1`,
+			`$.items[0]: unexpected property "extra"`,
+		}},
+		{`{"items": "ls"}`, []string{`$.items: expected an array`}},
+	}
+
+	for _, tt := range tests {
+		problems, err := ValidateSchema(testSchema, tt.data)
+		if err != nil {
+			t.Fatalf("ValidateSchema() error = %v", err)
+		}
+		if !reflect.DeepEqual(problems, tt.expected) {
+			t.Errorf("ValidateSchema(%s) = %q, want %q", tt.data, problems, tt.expected)
+		}
+	}
+}
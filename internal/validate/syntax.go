@@ -0,0 +1,37 @@
+package validate
+
+import (
+	"path/filepath"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// SyntaxError returns the parse error of command in the given shell, or nil.
+// Shells the parser does not understand (fish, PowerShell, cmd) are never
+// reported as errors.
+func SyntaxError(command, shell string) error {
+	variant, ok := shellVariant(shell)
+	if !ok {
+		return nil
+	}
+
+	parser := syntax.NewParser(syntax.Variant(variant))
+	_, err := parser.Parse(strings.NewReader(command), "")
+	return err
+}
+
+// shellVariant maps a shell name or path to a parser variant. The empty
+// shell is treated as bash.
+func shellVariant(shell string) (syntax.LangVariant, bool) {
+	switch strings.ToLower(filepath.Base(shell)) {
+	case "", ".", "bash", "zsh", "ksh":
+		return syntax.LangBash, true
+	case "sh", "dash", "ash", "posix":
+		return syntax.LangPOSIX, true
+	case "mksh":
+		return syntax.LangMirBSDKorn, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,25 @@
+package validate
+
+import "testing"
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		command string
+		shell   string
+		wantErr bool
+	}{
+		{"find . -name '*.txt' | xargs wc -l", "", false},
+		{"echo 'unterminated", "bash", true},
+		{"for f in *; do echo $f", "bash", true},
+		{"cat <<< \"hello\"", "sh", true},
+		{"cat <<< \"hello\"", "bash", false},
+		{"Get-ChildItem | Where-Object { $_.Length -gt 1MB }", "powershell", false},
+	}
+
+	for _, tt := range tests {
+		err := SyntaxError(tt.command, tt.shell)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SyntaxError(%q, %q) = %v, wantErr %v", tt.command, tt.shell, err, tt.wantErr)
+		}
+	}
+}
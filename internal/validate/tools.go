@@ -0,0 +1,190 @@
+package validate
+
+import (
+	"clify/internal/models"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// shellBuiltins are commands provided by the shell rather than $PATH
+var shellBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "[[": true, "alias": true, "bg": true,
+	"bind": true, "break": true, "builtin": true, "cd": true, "command": true,
+	"continue": true, "declare": true, "dirs": true, "disown": true, "echo": true,
+	"enable": true, "eval": true, "exec": true, "exit": true, "export": true,
+	"false": true, "fc": true, "fg": true, "getopts": true, "hash": true,
+	"help": true, "history": true, "jobs": true, "kill": true, "let": true,
+	"local": true, "logout": true, "popd": true, "printf": true, "pushd": true,
+	"pwd": true, "read": true, "readonly": true, "return": true, "set": true,
+	"shift": true, "shopt": true, "source": true, "test": true, "time": true,
+	"times": true, "trap": true, "true": true, "type": true, "typeset": true,
+	"ulimit": true, "umask": true, "unalias": true, "unset": true, "wait": true,
+}
+
+// wrapperCommands run the command that follows their own flags
+var wrapperCommands = map[string]bool{
+	"sudo": true, "env": true, "xargs": true, "time": true, "nohup": true,
+	"exec": true, "command": true, "nice": true, "watch": true, "doas": true,
+}
+
+// wrapperValueFlags are the flags of each wrapper that consume the
+// following word
+var wrapperValueFlags = map[string]map[string]bool{
+	"sudo":  {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-r": true, "-t": true, "-U": true},
+	"doas":  {"-u": true, "-C": true},
+	"env":   {"-u": true, "-C": true},
+	"xargs": {"-I": true, "-P": true, "-n": true, "-d": true, "-L": true, "-s": true, "-E": true, "-a": true},
+	"time":  {"-f": true, "-o": true},
+	"nice":  {"-n": true},
+	"watch": {"-n": true},
+	"exec":  {"-a": true},
+}
+
+// packageNames maps executables to package names where they differ
+var packageNames = map[string]map[string]string{
+	"fd":  {"apt": "fd-find", "dnf": "fd-find", "yum": "fd-find", "default": "fd"},
+	"rg":  {"default": "ripgrep"},
+	"bat": {"default": "bat"},
+	"ag":  {"apt": "silversearcher-ag", "brew": "the_silver_searcher", "default": "the_silver_searcher"},
+}
+
+// installCommands holds the install command template per package manager
+var installCommands = map[string]string{
+	"brew":   "brew install %s",
+	"port":   "sudo port install %s",
+	"apt":    "sudo apt install %s",
+	"dnf":    "sudo dnf install %s",
+	"yum":    "sudo yum install %s",
+	"pacman": "sudo pacman -S %s",
+	"zypper": "sudo zypper install %s",
+	"apk":    "sudo apk add %s",
+	"nix":    "nix-env -iA nixpkgs.%s",
+	"winget": "winget install %s",
+	"choco":  "choco install %s",
+	"scoop":  "scoop install %s",
+}
+
+// lookPath is swapped out in tests
+var (
+	lookPath        = exec.LookPath
+	defaultLookPath = exec.LookPath
+)
+
+// Executables returns the programs invoked by a shell command line, in order of
+// first appearance. Commands that fail to parse yield their first word.
+func Executables(command string) []string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return nil
+		}
+		return []string{fields[0]}
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		for i := 0; i < len(call.Args); i++ {
+			name := call.Args[i].Lit()
+			add(name)
+			if !wrapperCommands[name] {
+				break
+			}
+			// Skip the wrapper's own flags and variable assignments
+			for i+1 < len(call.Args) {
+				next := call.Args[i+1].Lit()
+				if !strings.HasPrefix(next, "-") && !strings.Contains(next, "=") {
+					break
+				}
+				i++
+				if wrapperValueFlags[name][next] {
+					i++
+				}
+			}
+		}
+		return true
+	})
+
+	return names
+}
+
+// MissingTools returns the executables in command that are neither shell
+// builtins nor found on $PATH
+func MissingTools(command string) []string {
+	var missing []string
+	for _, name := range Executables(command) {
+		if shellBuiltins[name] || strings.Contains(name, "/") {
+			continue
+		}
+		if _, err := lookPath(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// AnnotateMissingTools records missing executables on every command in response
+func AnnotateMissingTools(response *models.Response) {
+	if response == nil {
+		return
+	}
+	for i := range response.Commands {
+		response.Commands[i].MissingTools = MissingTools(response.Commands[i].Text)
+	}
+}
+
+// UnavailableTools collects the missing tools across all commands in response
+func UnavailableTools(response *models.Response) []string {
+	if response == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, cmd := range response.Commands {
+		for _, tool := range cmd.MissingTools {
+			seen[tool] = true
+		}
+	}
+
+	tools := make([]string, 0, len(seen))
+	for tool := range seen {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// InstallHint suggests how to install tool with the given package manager
+func InstallHint(tool, packageManager string) string {
+	template, ok := installCommands[packageManager]
+	if !ok {
+		return ""
+	}
+
+	pkg := tool
+	if names, ok := packageNames[tool]; ok {
+		if name, ok := names[packageManager]; ok {
+			pkg = name
+		} else {
+			pkg = names["default"]
+		}
+	}
+	return fmt.Sprintf(template, pkg)
+}
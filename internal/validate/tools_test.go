@@ -0,0 +1,73 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExecutables(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"ls -la", []string{"ls"}},
+		{"du -ah . | sort -hr | head -20", []string{"du", "sort", "head"}},
+		{"sudo -u root apt install nginx", []string{"sudo", "apt"}},
+		{"find . -name '*.go' | xargs -0 grep TODO", []string{"find", "xargs", "grep"}},
+		// -E and -n take no value for sudo, unlike for xargs
+		{"sudo -E make install", []string{"sudo", "make"}},
+		{"sudo -n systemctl restart nginx", []string{"sudo", "systemctl"}},
+		{"xargs -n 1 echo", []string{"xargs", "echo"}},
+		{"cd /tmp && fd -e txt", []string{"cd", "fd"}},
+		{"echo $(date)", []string{"echo", "date"}},
+		{"FOO=bar env LANG=C sort file", []string{"env", "sort"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			result := Executables(tt.command)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Executables(%q) = %v, want %v", tt.command, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMissingTools(t *testing.T) {
+	installed := map[string]bool{"ls": true, "sort": true}
+	lookPath = func(name string) (string, error) {
+		if installed[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+	defer func() { lookPath = defaultLookPath }()
+
+	result := MissingTools("cd /tmp && ls | sort | ncdu")
+	expected := []string{"ncdu"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("MissingTools() = %v, want %v", result, expected)
+	}
+}
+
+func TestInstallHint(t *testing.T) {
+	tests := []struct {
+		tool     string
+		pm       string
+		expected string
+	}{
+		{"fd", "apt", "sudo apt install fd-find"},
+		{"fd", "brew", "brew install fd"},
+		{"rg", "pacman", "sudo pacman -S ripgrep"},
+		{"ncdu", "apt", "sudo apt install ncdu"},
+		{"ncdu", "unknown", ""},
+	}
+
+	for _, tt := range tests {
+		result := InstallHint(tt.tool, tt.pm)
+		if result != tt.expected {
+			t.Errorf("InstallHint(%q, %q) = %q, want %q", tt.tool, tt.pm, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package workflow
+
+import (
+	"clify/internal/models"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// stateCommands change the shell they run in rather than the system, so
+// their effect ends with the step
+var stateCommands = map[string]bool{
+	"source": true, ".": true, "cd": true, "pushd": true, "popd": true,
+	"export": true, "unset": true, "alias": true, "set": true, "shopt": true,
+}
+
+// posixShells accept "cmd || exit $?" lines, so earlier steps can be run
+// again before a step
+var posixShells = map[string]bool{
+	"bash": true, "zsh": true, "ksh": true, "mksh": true, "sh": true, "dash": true, "ash": true,
+}
+
+// ChangesShellState reports whether a step only changes its own shell, e.g.
+// "source .venv/bin/activate", so that it has no effect on later steps
+// unless they run in the same shell
+func ChangesShellState(text string) bool {
+	fields := strings.Fields(text)
+	return len(fields) > 0 && stateCommands[fields[0]]
+}
+
+// Setup returns, in workflow order, the steps that step index depends on,
+// directly or through other steps, that done reports as run and that only
+// change the shell's state
+func Setup(workflow *models.Workflow, index int, done func(int) bool) []string {
+	needed := map[int]bool{}
+	var visit func(i int)
+	visit = func(i int) {
+		for _, dep := range workflow.Steps[i].DependsOn {
+			for j, step := range workflow.Steps {
+				if step.ID == dep && !needed[j] {
+					needed[j] = true
+					visit(j)
+				}
+			}
+		}
+	}
+	visit(index)
+
+	var setup []string
+	for i, step := range workflow.Steps {
+		if needed[i] && done(i) && ChangesShellState(step.Text) {
+			setup = append(setup, step.Text)
+		}
+	}
+	return setup
+}
+
+// CarriesState reports whether StepCommand runs the setup steps in shell
+func CarriesState(shell string) bool {
+	name := strings.ToLower(filepath.Base(shell))
+	return name == "" || name == "." || posixShells[name]
+}
+
+// StepCommand returns the command that runs a step in shell, or in bash,
+// the dialect steps are checked and exported in, if shell is "". In POSIX
+// shells the setup steps run first in the same shell, so that e.g. an
+// activated virtual environment carries over; other shells ignore them.
+func StepCommand(shell, text string, setup []string) *exec.Cmd {
+	name := strings.ToLower(filepath.Base(shell))
+	switch name {
+	case "powershell", "pwsh":
+		return exec.Command(shell, "-NoProfile", "-Command", text)
+	case "cmd":
+		return exec.Command(shell, "/C", text)
+	case "", ".":
+		shell, name = "bash", "bash"
+	}
+
+	if posixShells[name] && len(setup) > 0 {
+		var b strings.Builder
+		for _, s := range setup {
+			b.WriteString(s)
+			b.WriteString(" || exit $?\n")
+		}
+		b.WriteString(text)
+		text = b.String()
+	}
+	return exec.Command(shell, "-c", text)
+}
@@ -0,0 +1,52 @@
+package workflow
+
+import (
+	"clify/internal/models"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetupCarriesShellState(t *testing.T) {
+	wf := &models.Workflow{Steps: []models.WorkflowStep{
+		{ID: 1, Text: "python3 -m venv .venv"},
+		{ID: 2, Text: "source .venv/bin/activate", DependsOn: []int{1}},
+		{ID: 3, Text: "cd /tmp"},
+		{ID: 4, Text: "pip install -r requirements.txt", DependsOn: []int{2}},
+	}}
+	done := func(int) bool { return true }
+
+	if got := Setup(wf, 3, done); !slices.Equal(got, []string{"source .venv/bin/activate"}) {
+		t.Errorf("Setup() = %q, want the activation only", got)
+	}
+	if got := Setup(wf, 3, func(i int) bool { return i != 1 }); len(got) != 0 {
+		t.Errorf("Setup() with the activation skipped = %q, want none", got)
+	}
+}
+
+func TestStepCommand(t *testing.T) {
+	setup := []string{"source .venv/bin/activate"}
+	tests := []struct {
+		shell string
+		args  []string
+	}{
+		{"", []string{"bash", "-c", "source .venv/bin/activate || exit $?\npip install x"}},
+		{"/bin/zsh", []string{"/bin/zsh", "-c", "source .venv/bin/activate || exit $?\npip install x"}},
+		{"fish", []string{"fish", "-c", "pip install x"}},
+		{"powershell", []string{"powershell", "-NoProfile", "-Command", "pip install x"}},
+	}
+	for _, tt := range tests {
+		if got := StepCommand(tt.shell, "pip install x", setup).Args; !slices.Equal(got, tt.args) {
+			t.Errorf("StepCommand(%q) args = %q, want %q", tt.shell, got, tt.args)
+		}
+	}
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	out, err := StepCommand("", `[[ -n "$GREETING" ]] && echo "$GREETING"`, []string{"export GREETING=hello"}).Output()
+	if err != nil || strings.TrimSpace(string(out)) != "hello" {
+		t.Errorf("running a bash step = %q, %v, want the exported variable", out, err)
+	}
+}
@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"clify/internal/models"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Script renders workflow as a bash script that stops at the first failing step
+func Script(query string, workflow *models.Workflow) string {
+	var b strings.Builder
+
+	b.WriteString("#!/usr/bin/env bash\n")
+	b.WriteString("set -euo pipefail\n")
+	b.WriteString("\n")
+	b.WriteString(comment(fmt.Sprintf("Generated by clify: %s", query)))
+	if workflow.Explanation != "" {
+		b.WriteString(comment(workflow.Explanation))
+	}
+
+	for _, step := range workflow.Steps {
+		b.WriteString("\n")
+		b.WriteString(comment(fmt.Sprintf("Step %d: %s", step.ID, step.Description)))
+		if len(step.DependsOn) > 0 {
+			deps := make([]string, len(step.DependsOn))
+			for i, dep := range step.DependsOn {
+				deps[i] = fmt.Sprintf("%d", dep)
+			}
+			b.WriteString(comment(fmt.Sprintf("Depends on: step %s", strings.Join(deps, ", "))))
+		}
+		if step.SafetyLevel != "" && step.SafetyLevel != string(models.SafetyLevelSafe) {
+			b.WriteString(comment(fmt.Sprintf("Safety: %s", step.SafetyLevel)))
+		}
+		b.WriteString(step.Text)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Export writes the workflow script to a new executable file at path. An
+// existing file is never replaced; the error then wraps fs.ErrExist.
+func Export(path, query string, workflow *models.Workflow) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0755)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists: %w", path, fs.ErrExist)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create script: %w", err)
+	}
+	if _, err := f.WriteString(Script(query, workflow)); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write script: %w", err)
+	}
+	return f.Close()
+}
+
+// ExportNew writes the workflow script to FileName(query) in the current
+// directory, adding a numeric suffix (name-2.sh, ...) if that file exists.
+// It returns the path written.
+func ExportNew(query string, workflow *models.Workflow) (string, error) {
+	base := strings.TrimSuffix(FileName(query), ".sh")
+	path := base + ".sh"
+	for n := 2; ; n++ {
+		err := Export(path, query, workflow)
+		if !errors.Is(err, fs.ErrExist) {
+			return path, err
+		}
+		if n > 100 {
+			return "", err
+		}
+		path = fmt.Sprintf("%s-%d.sh", base, n)
+	}
+}
+
+// FileName derives a script file name from the query
+func FileName(query string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(query) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteRune('-')
+			lastDash = true
+		}
+		if b.Len() >= 40 {
+			break
+		}
+	}
+
+	name := strings.Trim(b.String(), "-")
+	if name == "" {
+		name = "workflow"
+	}
+	return name + ".sh"
+}
+
+// comment prefixes every line of text with "# "
+func comment(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString("# ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"clify/internal/models"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	wf := &models.Workflow{
+		Explanation: "Create a virtual environment and install dependencies",
+		Steps: []models.WorkflowStep{
+			{ID: 1, Text: "python3 -m venv .venv", Description: "Create the venv", SafetyLevel: "warning"},
+			{ID: 2, Text: ".venv/bin/pip install -r requirements.txt", Description: "Install requirements", DependsOn: []int{1}, SafetyLevel: "warning"},
+		},
+	}
+
+	expected := `#!/usr/bin/env bash
+set -euo pipefail
+
+# Generated by clify: set up a python venv
+# Create a virtual environment and install dependencies
+
+# Step 1: Create the venv
+# Safety: warning
+python3 -m venv .venv
+
+# Step 2: Install requirements
+# Depends on: step 1
+# Safety: warning
+.venv/bin/pip install -r requirements.txt
+`
+
+	if result := Script("set up a python venv", wf); result != expected {
+		t.Errorf("Script() =\n%s\nwant\n%s", result, expected)
+	}
+}
+
+func TestExportNeverOverwrites(t *testing.T) {
+	t.Chdir(t.TempDir())
+	wf := &models.Workflow{Steps: []models.WorkflowStep{{ID: 1, Text: "echo hi"}}}
+	if err := os.WriteFile("say-hi.sh", []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Export("say-hi.sh", "say hi", wf); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Export() over an existing file: error = %v, want fs.ErrExist", err)
+	}
+	if data, _ := os.ReadFile("say-hi.sh"); string(data) != "keep me" {
+		t.Errorf("existing file = %q, want it untouched", data)
+	}
+
+	for _, want := range []string{"say-hi-2.sh", "say-hi-3.sh"} {
+		if path, err := ExportNew("say hi", wf); err != nil || path != want {
+			t.Errorf("ExportNew() = %q, %v; want %q", path, err, want)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"Set up a Python venv & install requirements", "set-up-a-python-venv-install-requirement.sh"},
+		{"!!!", "workflow.sh"},
+	}
+
+	for _, tt := range tests {
+		if result := FileName(tt.query); result != tt.expected {
+			t.Errorf("FileName(%q) = %q, want %q", tt.query, result, tt.expected)
+		}
+	}
+}
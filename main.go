@@ -4,7 +4,14 @@ import (
 	"clify/internal/client"
 	"clify/internal/commands"
 	"clify/internal/config"
+	"clify/internal/models"
+	"clify/internal/query"
+	"clify/internal/snippets"
 	"clify/internal/tui"
+	"clify/internal/workflow"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
@@ -12,14 +19,73 @@ import (
 	"github.com/charmbracelet/bubbletea"
 )
 
+// options holds the global flags accepted before a command or query
+type options struct {
+	json        bool
+	verifyFlags bool
+	workflow    bool
+	offline     bool
+	export      string
+	target      models.Target
+	profile     string
+}
+
+func parseOptions(args []string) (options, []string) {
+	var opts options
+	var help, version bool
+
+	fs := flag.NewFlagSet("clify", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = showHelp
+	fs.BoolVar(&opts.json, "json", false, "print the response as JSON instead of starting the TUI")
+	fs.BoolVar(&opts.verifyFlags, "verify-flags", false, "check flags against local man pages and --help output")
+	fs.BoolVar(&opts.workflow, "workflow", false, "ask for an ordered multi-step workflow")
+	fs.BoolVar(&opts.offline, "offline", false, "answer from cached responses and the bundled catalog without the API")
+	fs.StringVar(&opts.export, "export", "", "write the workflow as a shell script to this file")
+	fs.StringVar(&opts.target.OS, "os", "", "generate commands for this OS (linux, macos, windows, ...)")
+	fs.StringVar(&opts.target.Shell, "shell", "", "generate commands for this shell (bash, zsh, fish, powershell, ...)")
+	fs.StringVar(&opts.target.Distro, "distro", "", "generate commands for this distribution (debian, alpine, ...)")
+	fs.StringVar(&opts.profile, "profile", "", "use this profile from the config file (default $CLIFY_PROFILE or by directory)")
+	fs.BoolVar(&help, "h", false, "show help")
+	fs.BoolVar(&help, "help", false, "show help")
+	fs.BoolVar(&version, "v", false, "show version")
+	fs.BoolVar(&version, "version", false, "show version")
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+
+	rest := fs.Args()
+	if help {
+		rest = []string{"help"}
+	} else if version {
+		rest = []string{"version"}
+	}
+	return opts, rest
+}
+
 func main() {
-	if len(os.Args) < 2 {
+	opts, args := parseOptions(os.Args[1:])
+	config.SetTargetOverride(opts.target)
+	config.SetProfileOverride(opts.profile)
+	config.SetOfflineOverride(opts.offline)
+	for _, warning := range config.PermissionWarnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	for _, warning := range config.ProjectWarnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	if len(args) < 1 {
 		// Interactive mode
-		runInteractiveMode()
+		runInteractiveMode(opts)
 		return
 	}
 
-	command := os.Args[1]
+	command := args[0]
 
 	switch command {
 	case "setup":
@@ -36,40 +102,149 @@ func main() {
 			os.Exit(1)
 		}
 
-	case "help", "--help", "-h":
+	case "context":
+		contextCmd := commands.NewContextCommand()
+		if err := contextCmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Context failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "usage":
+		usageCmd := commands.NewUsageCommand()
+		if err := usageCmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Usage failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "cache":
+		cacheCmd := commands.NewCacheCommand()
+		if err := cacheCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Cache failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "history":
+		historyCmd := commands.NewHistoryCommand()
+		if err := historyCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "History failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "fav":
+		favCmd := commands.NewFavCommand()
+		if err := favCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Fav failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "encryption":
+		encryptionCmd := commands.NewEncryptionCommand()
+		if err := encryptionCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Encryption failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "config":
+		configCmd := commands.NewConfigCommand()
+		if err := configCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Config failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "snippets":
+		snippetsCmd := commands.NewSnippetsCommand()
+		if err := snippetsCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Snippets failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "eval":
+		evalCmd := commands.NewEvalCommand()
+		if err := evalCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Eval failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "prompt":
+		promptCmd := commands.NewPromptCommand()
+		if err := promptCmd.Run(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Prompt failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "help":
 		showHelp()
 
-	case "version", "--version", "-v":
+	case "version":
 		showVersion()
 
 	default:
 		// Direct query mode
-		query := strings.Join(os.Args[1:], " ")
-		runDirectQuery(query)
+		query := strings.Join(args, " ")
+		runDirectQuery(query, opts)
 	}
 }
 
-func setupAndRunTUI(query string) {
+// newQueryService checks setup and builds the query service from config
+func newQueryService(opts options) (*query.Service, *config.History) {
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err == nil && !cfg.Offline {
+		err = config.ResolveAPIKey(cfg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Check if setup is required
 	setupCmd := commands.NewSetupCommand()
-	if setupCmd.IsSetupRequired() {
+	if !cfg.Offline && setupCmd.IsSetupRequired(cfg) {
 		setupCmd.ShowSetupPrompt()
-		return
+		os.Exit(1)
 	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	// Initialize clients
+	claudeClient, err := client.NewClaudeClientFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create client: %v\n", err)
+		os.Exit(1)
+	}
+	cache, err := config.NewCacheManagerFromConfig(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
+	go cache.Compact() // evictions are best effort; the next save retries
 
-	// Initialize clients
-	claudeClient := client.NewClaudeClient(cfg.APIKey)
-	cache := config.NewCacheManager()
+	service := query.NewService(claudeClient, cache)
+	usage := config.NewUsageTracker()
+	usage.SetProfile(cfg.Profile)
+	service.TrackUsage(usage, cfg.Budget)
+	service.SetOffline(cfg.Offline)
+	service.SetSimilarityThreshold(cfg.SimilarityThreshold)
+	service.SetProfile(cfg.Profile)
+	service.SetFavorites(config.NewFavorites())
+	if len(cfg.Snippets.Sources) > 0 {
+		index, errs := snippets.Load(cfg.Snippets.Sources)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Warning: skipped snippets: %v (see clify snippets lint)\n", err)
+		}
+		service.SetSnippets(index)
+	}
+	if cfg.VerifyFlags || opts.verifyFlags {
+		service.EnableFlagVerification()
+	}
+	return service, config.NewHistory(cfg.HistorySize)
+}
+
+func setupAndRunTUI(query string, opts options) {
+	service, history := newQueryService(opts)
 
 	// Create TUI model
-	model := tui.NewModel(claudeClient, cache)
+	model := tui.NewModel(service, history)
+
+	model.SetWorkflowMode(opts.workflow)
 
 	// Set initial query if provided
 	if query != "" {
@@ -78,37 +253,129 @@ func setupAndRunTUI(query string) {
 
 	// Run the TUI
 	p := tea.NewProgram(model, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	_, err := p.Run()
+	flushService(service)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func runInteractiveMode() {
-	setupAndRunTUI("")
+// flushService saves what the service writes lazily before exiting
+func flushService(service *query.Service) {
+	if err := service.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save cache: %v\n", err)
+	}
+}
+
+func runInteractiveMode(opts options) {
+	setupAndRunTUI("", opts)
 }
 
-func runDirectQuery(query string) {
-	setupAndRunTUI(query)
+func runDirectQuery(query string, opts options) {
+	if opts.workflow && (opts.json || opts.export != "") {
+		runWorkflowQuery(query, opts)
+		return
+	}
+	if opts.json {
+		runJSONQuery(query, opts)
+		return
+	}
+	setupAndRunTUI(query, opts)
+}
+
+// runJSONQuery prints the response for query as JSON without starting the TUI
+func runJSONQuery(query string, opts options) {
+	service, history := newQueryService(opts)
+	if _, err := history.Add(query); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record history: %v\n", err)
+	}
+
+	response, err := service.Query(context.Background(), query)
+	flushService(service)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(response); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode response: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runWorkflowQuery prints or exports a workflow without starting the TUI
+func runWorkflowQuery(query string, opts options) {
+	service, _ := newQueryService(opts)
+
+	wf, err := service.QueryWorkflow(context.Background(), query)
+	flushService(service)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Query failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.export != "" {
+		if err := workflow.Export(opts.export, query, wf); err != nil {
+			fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Saved to %s\n", opts.export)
+	}
+
+	if opts.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(wf); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode workflow: %v\n", err)
+			os.Exit(1)
+		}
+	}
 }
 
 func showHelp() {
 	fmt.Println("clify - AI-powered command-line helper")
 	fmt.Println()
 	fmt.Println("USAGE:")
-	fmt.Println("  clify [command|query]")
+	fmt.Println("  clify [options] [command|query]")
+	fmt.Println()
+	fmt.Println("OPTIONS:")
+	fmt.Println("  --json          Print the response as JSON instead of starting the TUI")
+	fmt.Println("  --verify-flags  Check flags against local man pages and --help output")
+	fmt.Println("  --workflow      Ask for ordered steps instead of alternatives")
+	fmt.Println("  --export FILE   Write the workflow as a shell script (with --workflow)")
+	fmt.Println("  --offline       Answer from past responses and the bundled catalog only")
+	fmt.Println("  --os OS         Generate commands for another OS (linux, macos, windows)")
+	fmt.Println("  --shell SHELL   Generate commands for another shell")
+	fmt.Println("  --distro NAME   Generate commands for a Linux distribution")
+	fmt.Println("  --profile NAME  Use a profile from the config file")
 	fmt.Println()
 	fmt.Println("COMMANDS:")
-	fmt.Println("  setup     Interactive setup wizard")
-	fmt.Println("  tutorial  Interactive tutorial")
-	fmt.Println("  help      Show this help message")
-	fmt.Println("  version   Show version information")
+	fmt.Println("  setup       Interactive setup wizard")
+	fmt.Println("  tutorial    Interactive tutorial")
+	fmt.Println("  context     Show the environment details sent with queries")
+	fmt.Println("  usage       Show token usage, cost and budget")
+	fmt.Println("  cache       Manage the cache (stats, clear, compact, pin, unpin, export, import)")
+	fmt.Println("  history     Manage past queries (list, search, delete, clear, export, import)")
+	fmt.Println("  fav         Manage favorite commands (list, add, rm, run, export, import)")
+	fmt.Println("  encryption  Encrypt the cache, history and usage log at rest (status, enable, migrate, disable)")
+	fmt.Println("  config      Show the effective configuration (config show [--origin])")
+	fmt.Println("  snippets    Check team snippet files (snippets lint)")
+	fmt.Println("  eval        Score a query suite and compare with the previous run")
+	fmt.Println("  prompt      Show the rendered prompt for a query (prompt show <query>)")
+	fmt.Println("  help        Show this help message")
+	fmt.Println("  version     Show version information")
 	fmt.Println()
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  clify                           # Interactive mode")
 	fmt.Println("  clify \"find all .txt files\"     # Direct query")
 	fmt.Println("  clify \"kill process on port 8080\"")
 	fmt.Println("  clify \"compress folder to zip\"")
+	fmt.Println("  clify --json \"list open ports\"  # Machine-readable output")
+	fmt.Println("  clify --workflow --export setup.sh \"set up a python venv\"")
+	fmt.Println("  clify --os linux --distro alpine \"install curl\"  # Dockerfile RUN line")
 	fmt.Println()
 	fmt.Println("SAFETY:")
 	fmt.Println("  Commands are color-coded for safety:")